@@ -4,24 +4,20 @@ import (
 	"context"
 	"fmt"
 	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/Gskartwii/roblox-dissector/util/capture"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/olebedev/emitter"
 	"net"
+	"os"
 )
 
-type Conversation struct {
-	Client       *net.UDPAddr
-	Server       *net.UDPAddr
-	ClientReader PacketProvider
-	ServerReader PacketProvider
-	Context      *peer.CommunicationContext
-}
-
 type CaptureSession struct {
 	Name                  string
 	ViewerCounter         uint
 	IsCapturing           bool
-	Conversations         []*Conversation
+	Conversations         []*capture.Conversation
 	CancelFunc            context.CancelFunc
 	InitialViewerOccupied bool
 	ListViewers           []*PacketListViewer
@@ -31,10 +27,6 @@ type CaptureSession struct {
 	ForgetAcks            bool
 }
 
-func AddressEq(a *net.UDPAddr, b *net.UDPAddr) bool {
-	return a.Port == b.Port && a.IP.Equal(b.IP)
-}
-
 func NewCaptureSession(name string, cancelFunc context.CancelFunc, listViewerCallback func(*CaptureSession, *PacketListViewer, error)) (*CaptureSession, error) {
 	initialViewer, err := NewPacketListViewer(fmt.Sprintf("%s#%d", name, 1), nil)
 	if err != nil {
@@ -59,46 +51,27 @@ func (session *CaptureSession) SetProgress(prog int) {
 	session.progress = prog
 }
 
-func (session *CaptureSession) ConversationFor(source *net.UDPAddr, dest *net.UDPAddr, payload []byte) *Conversation {
+func (session *CaptureSession) ConversationFor(source *net.UDPAddr, dest *net.UDPAddr, payload []byte) *capture.Conversation {
 	for _, conv := range session.Conversations {
-		if AddressEq(source, conv.Client) && AddressEq(dest, conv.Server) {
+		if capture.AddressEq(source, conv.Client) && capture.AddressEq(dest, conv.Server) {
 			return conv
 		}
-		if AddressEq(source, conv.Server) && AddressEq(dest, conv.Client) {
+		if capture.AddressEq(source, conv.Server) && capture.AddressEq(dest, conv.Client) {
 			return conv
 		}
 	}
 
-	if len(payload) < 1 || payload[0] != 0x7B {
+	newConv := capture.NewConversation(source, dest, payload, latestRobloxAPI)
+	if newConv == nil {
 		return nil
 	}
-	isHandshake := peer.IsOfflineMessage(payload)
-	if !isHandshake {
-		return nil
-	}
-
-	newContext := peer.NewCommunicationContext()
-	clientR := peer.NewPacketReader()
-	serverR := peer.NewPacketReader()
-	clientR.SetContext(newContext)
-	serverR.SetContext(newContext)
-	clientR.SetIsClient(true)
-	clientR.BindDataModelHandlers()
-	serverR.BindDataModelHandlers()
-	newConv := &Conversation{
-		Client:       source,
-		Server:       dest,
-		ClientReader: clientR,
-		ServerReader: serverR,
-		Context:      newContext,
-	}
 	session.Conversations = append(session.Conversations, newConv)
 	session.AddConversation(newConv)
 
 	return newConv
 }
 
-func (session *CaptureSession) AddConversation(conv *Conversation) (*PacketListViewer, error) {
+func (session *CaptureSession) AddConversation(conv *capture.Conversation) (*PacketListViewer, error) {
 	var err error
 	var viewer *PacketListViewer
 	if !session.InitialViewerOccupied {
@@ -147,6 +120,9 @@ func (session *CaptureSession) StopCapture() {
 }
 
 func (session *CaptureSession) ReportDone() {
+	for i, conv := range session.Conversations {
+		capture.ReportUnresolvedReferences(os.Stdout, i+1, conv)
+	}
 	glib.IdleAdd(func() bool {
 		session.IsCapturing = false
 		if session.ProgressCallback != nil {
@@ -155,3 +131,13 @@ func (session *CaptureSession) ReportDone() {
 		return false
 	})
 }
+
+func CaptureFromHandle(ctx context.Context, convs capture.Conversations, handle *pcap.Handle) error {
+	err := handle.SetBPFFilter("udp")
+	if err != nil {
+		return err
+	}
+
+	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
+	return capture.CaptureFromSource(ctx, convs, packetSource)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+const (
+	COL_DIFF_CHANGE = iota
+	COL_DIFF_PATH
+	COL_DIFF_CLASS
+	COL_DIFF_OLD
+	COL_DIFF_NEW
+)
+
+func appendDiffRow(model *gtk.TreeStore, parent *gtk.TreeIter, change string, path string, class string, oldValue string, newValue string) *gtk.TreeIter {
+	row := model.Append(parent)
+	model.SetValue(row, COL_DIFF_CHANGE, change)
+	model.SetValue(row, COL_DIFF_PATH, path)
+	model.SetValue(row, COL_DIFF_CLASS, class)
+	model.SetValue(row, COL_DIFF_OLD, oldValue)
+	model.SetValue(row, COL_DIFF_NEW, newValue)
+	return row
+}
+
+// ShowDataModelDiff opens a window that lists the differences between
+// the old and the new DataModel
+func ShowDataModelDiff(oldModel *datamodel.DataModel, newModel *datamodel.DataModel) error {
+	diff := datamodel.Diff(oldModel, newModel)
+
+	model, err := gtk.TreeStoreNew(
+		glib.TYPE_STRING, // COL_DIFF_CHANGE
+		glib.TYPE_STRING, // COL_DIFF_PATH
+		glib.TYPE_STRING, // COL_DIFF_CLASS
+		glib.TYPE_STRING, // COL_DIFF_OLD
+		glib.TYPE_STRING, // COL_DIFF_NEW
+	)
+	if err != nil {
+		return err
+	}
+	for _, inst := range diff.Added {
+		appendDiffRow(model, nil, "Added", inst.GetFullName(), inst.ClassName, "", "")
+	}
+	for _, inst := range diff.Removed {
+		appendDiffRow(model, nil, "Removed", inst.GetFullName(), inst.ClassName, "", "")
+	}
+	for _, change := range diff.Changed {
+		changeType := "Changed"
+		oldPath := ""
+		if change.Reparented {
+			changeType = "Reparented"
+			oldPath = change.Old.GetFullName()
+		}
+		row := appendDiffRow(model, nil, changeType, change.New.GetFullName(), change.New.ClassName, oldPath, "")
+		for _, property := range change.Properties {
+			appendDiffRow(model, row, "Property", property.Name, datamodel.TypeString(property.New), property.OldString(), property.NewString())
+		}
+	}
+
+	treeView, err := gtk.TreeViewNewWithModel(model)
+	if err != nil {
+		return err
+	}
+	for i, colName := range []string{"Change", "Name", "Class/Type", "Old", "New"} {
+		colRenderer, err := gtk.CellRendererTextNew()
+		if err != nil {
+			return err
+		}
+		col, err := gtk.TreeViewColumnNewWithAttribute(
+			colName,
+			colRenderer,
+			"text",
+			i,
+		)
+		if err != nil {
+			return err
+		}
+		col.SetSortColumnID(i)
+		col.SetResizable(true)
+
+		treeView.AppendColumn(col)
+	}
+	treeView.SetVExpand(true)
+	treeView.SetHExpand(true)
+
+	scrolled, err := gtk.ScrolledWindowNew(nil, nil)
+	if err != nil {
+		return err
+	}
+	scrolled.Add(treeView)
+
+	win, err := gtk.WindowNew(gtk.WINDOW_TOPLEVEL)
+	if err != nil {
+		return err
+	}
+
+	box, err := boxWithMargin()
+	if err != nil {
+		return err
+	}
+	summary, err := newLabelF("%d added, %d removed, %d changed", len(diff.Added), len(diff.Removed), len(diff.Changed))
+	if err != nil {
+		return err
+	}
+	box.Add(summary)
+	box.Add(scrolled)
+
+	buttonRow, err := gtk.ButtonBoxNew(gtk.ORIENTATION_HORIZONTAL)
+	if err != nil {
+		return err
+	}
+	buttonRow.SetLayout(gtk.BUTTONBOX_END)
+	buttonRow.SetSpacing(8)
+	saveButton, err := gtk.ButtonNewWithLabel("Save as JSON...")
+	if err != nil {
+		return err
+	}
+	saveButton.Connect("clicked", func() {
+		chooser, err := gtk.FileChooserNativeDialogNew("Save diff", win, gtk.FILE_CHOOSER_ACTION_SAVE, "Save", "Cancel")
+		if err != nil {
+			ShowError(win, err, "Making chooser")
+			return
+		}
+		chooser.SetCurrentName("diff.json")
+
+		resp := chooser.NativeDialog.Run()
+		if gtk.ResponseType(resp) != gtk.RESPONSE_ACCEPT {
+			return
+		}
+		contents, err := json.MarshalIndent(diff, "", "\t")
+		if err != nil {
+			ShowError(win, err, "Error while encoding diff")
+			return
+		}
+		err = ioutil.WriteFile(chooser.GetFilename(), contents, 0644)
+		if err != nil {
+			ShowError(win, err, "Error while saving diff")
+		}
+	})
+	buttonRow.Add(saveButton)
+
+	okButton, err := gtk.ButtonNewWithLabel("OK")
+	if err != nil {
+		return err
+	}
+	okButton.Connect("clicked", func() {
+		win.Destroy()
+	})
+	buttonRow.Add(okButton)
+
+	box.Add(buttonRow)
+	win.Add(box)
+	win.SetSizeRequest(800, 640)
+	win.SetTitle("DataModel diff")
+	win.ShowAll()
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/Gskartwii/roblox-dissector/datamodel"
 	"github.com/Gskartwii/roblox-dissector/peer"
 	"github.com/dustin/go-humanize"
 	"github.com/gotk3/gotk3/glib"
@@ -27,9 +28,12 @@ func blanketViewer(content string) (gtk.IWidget, error) {
 	return viewer, nil
 }
 
-func viewerForDataPacket(packet peer.Packet83Subpacket) (gtk.IWidget, error) {
+func viewerForDataPacket(packet peer.Packet83Subpacket, history *datamodel.PropertyHistory) (gtk.IWidget, error) {
+	if _, ok := packet.(*peer.OpaqueSubpacket); ok {
+		return blanketViewer(packet.String())
+	}
 	switch packet.Type() {
-	case 0x01, 0x04, 0x09, 0x0B, 0x0C, 0x0D, 0x0F, 0x10, 0x13, 0x14:
+	case 0x01, 0x04, 0x08, 0x09, 0x0B, 0x0C, 0x0D, 0x0F, 0x10, 0x13, 0x14:
 		return blanketViewer(packet.String())
 	case 0x02:
 		newInst := packet.(*peer.Packet83_02)
@@ -53,6 +57,7 @@ func viewerForDataPacket(packet peer.Packet83Subpacket) (gtk.IWidget, error) {
 		}
 
 		viewer.ViewPropertyUpdate(prop.Instance, prop.Schema.Name, prop.Value, version)
+		viewer.ViewPropertyHistory(history.Get(prop.Instance, prop.Schema.Name))
 		viewer.mainWidget.ShowAll()
 		return viewer.mainWidget, nil
 	case 0x05:
@@ -8,16 +8,21 @@ import (
 	"math/rand"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/Gskartwii/roblox-dissector/datamodel"
 	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/Gskartwii/roblox-dissector/util/capture"
 	"github.com/dreadl0ck/gopcap"
 	"github.com/google/gopacket/pcap"
 	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/bin"
 	"github.com/robloxapi/rbxfile/xml"
 )
 
@@ -199,7 +204,40 @@ func (win *DissectorWindow) PauseClicked() {
 func (win *DissectorWindow) BrowseDataModelClicked() {
 	curPage := win.tabs.GetCurrentPage()
 	currViewer := win.tabIndexToListViewer[curPage]
-	BrowseDataModel(currViewer.Conversation.Context)
+	BrowseDataModel(currViewer.Conversation.Context, win.Conversations)
+}
+
+// Conversations returns the conversations shown in the tabs
+func (win *DissectorWindow) Conversations() []*capture.Conversation {
+	var conversations []*capture.Conversation
+	seen := make(map[*capture.Conversation]bool)
+	for _, viewer := range win.tabIndexToListViewer {
+		if viewer.Conversation != nil && !seen[viewer.Conversation] {
+			seen[viewer.Conversation] = true
+			conversations = append(conversations, viewer.Conversation)
+		}
+	}
+	return conversations
+}
+
+// readPlace reads a RBXL or RBXLX place or a project folder
+func readPlace(location string) (*rbxfile.Root, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return datamodel.ReadProject(location)
+	}
+	file, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(location), ".rbxl") {
+		return bin.DeserializePlace(file, nil)
+	}
+	return xml.Deserialize(file, nil)
 }
 
 func (win *DissectorWindow) CaptureFromPcapDevice(name string) {
@@ -657,32 +695,44 @@ Sala is tool for dissecting Roblox network packets.`)
 		return nil, invalidUi("startserveritem")
 	}
 	startServerItem.Connect("activate", func() {
-		err := NewServerStartWidget(func(schemaLocation string, rbxlxLocation string, port uint16) {
+		err := NewServerStartWidget(func(schemaLocation string, typeMappingLocation string, rbxlxLocation string, port uint16) {
+			var typeMapping map[string]uint8
+			if typeMappingLocation != "" {
+				file, err := os.Open(typeMappingLocation)
+				if err != nil {
+					ShowError(dwin, err, "Parsing type mapping")
+					return
+				}
+				typeMapping, err = peer.ParseAPITypeMapping(file)
+				file.Close()
+				if err != nil {
+					ShowError(dwin, err, "Parsing type mapping")
+					return
+				}
+			}
 			file, err := os.Open(schemaLocation)
 			if err != nil {
 				ShowError(dwin, err, "Parsing schema")
 				return
 			}
-			schema, err := peer.ParseSchema(file)
+			schema, warnings, err := peer.LoadSchemaWithMapping(file, typeMapping)
 			if err != nil {
 				ShowError(dwin, err, "Parsing schema")
 				return
 			}
-			dataModelReader, err := os.Open(rbxlxLocation)
-			if err != nil {
-				ShowError(dwin, err, "Parsing rbxlx")
-				return
+			for _, warning := range warnings {
+				fmt.Println("Schema generation warning:", warning)
 			}
-			dataModelRoot, err := xml.Deserialize(dataModelReader, nil)
+			dataModelRoot, err := readPlace(rbxlxLocation)
 			if err != nil {
-				ShowError(dwin, err, "Parsing rbxlx")
+				ShowError(dwin, err, "Reading place")
 				return
 			}
 
 			rand.Seed(time.Now().UnixNano())
 			instanceDictionary := datamodel.NewInstanceDictionary(1)
 			thisRoot := datamodel.FromRbxfile(instanceDictionary, dataModelRoot)
-			normalizeRoot(thisRoot, schema)
+			peer.NormalizeDataModel(thisRoot, schema)
 			err = dwin.CaptureFromServer(port, schema, thisRoot, instanceDictionary)
 			if err != nil {
 				ShowError(dwin, err, "Starting server")
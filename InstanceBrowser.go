@@ -3,19 +3,18 @@ package main
 import (
 	"github.com/Gskartwii/roblox-dissector/datamodel"
 	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/Gskartwii/roblox-dissector/util/capture"
 	"github.com/gotk3/gotk3/cairo"
 	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/gotk3/gotk3/pango"
 	"github.com/robloxapi/rbxfile"
-	"github.com/robloxapi/rbxfile/xml"
 
 	"encoding/hex"
 	"fmt"
 	"os"
 	"strconv"
-	"strings"
 )
 
 const (
@@ -423,6 +422,30 @@ func (viewer *PropEventViewer) ViewPropertyUpdate(instance *datamodel.Instance,
 	viewer.model.Clear()
 	appendValueRow(viewer.model, nil, name, newValue, viewer.view)
 }
+
+// ViewPropertyHistory shows the previous values of the property, newest first
+func (viewer *PropEventViewer) ViewPropertyHistory(history []datamodel.PropertyRecord) {
+	if len(history) == 0 {
+		return
+	}
+	historyRow := viewer.model.Append(nil)
+	viewer.model.SetValue(historyRow, COL_PROP_NAME, "History")
+	viewer.model.SetValue(historyRow, COL_PROP_TYPE, "")
+	viewer.model.SetValue(historyRow, COL_PROP_VALUE, fmt.Sprintf("%d values", len(history)))
+	viewer.model.SetValue(historyRow, COL_SHOW_PIXBUF, false)
+	viewer.model.SetValue(historyRow, COL_PROP_ADDITIONAL_VALUE, "")
+	for i := len(history) - 1; i >= 0; i-- {
+		record := history[i]
+		if record.Value == nil {
+			continue
+		}
+		name := fmt.Sprintf("Packet %d (%s, %s)", record.UniqueID, record.Direction(), record.Timestamp.Format("15:04:05.000"))
+		if record.Version != -1 {
+			name += fmt.Sprintf(" v%d", record.Version)
+		}
+		appendValueRow(viewer.model, historyRow, name, record.Value, viewer.view)
+	}
+}
 func (viewer *PropEventViewer) ViewEvent(instance *datamodel.Instance, name string, arguments []rbxfile.Value) {
 	viewer.name.SetText(name)
 	viewer.id.SetText("ID: " + instance.Ref.String())
@@ -601,128 +624,16 @@ func addInstances(dataTreeModel *gtk.TreeStore, parent *gtk.TreeIter, instances
 	}
 }
 
-type InstanceProperty struct {
-	Instance *rbxfile.Instance
-	Name     string
-}
-
-func findScripts(instances []*rbxfile.Instance, propertyList []InstanceProperty) []InstanceProperty {
-	for _, instance := range instances {
-		for name, property := range instance.Properties {
-			thisType := property.Type()
-			if thisType == datamodel.TypeSignedProtectedString {
-				propertyList = append(propertyList, InstanceProperty{
-					Instance: instance,
-					Name:     name,
-				})
-			}
-		}
-		propertyList = findScripts(instance.Children, propertyList)
-	}
-	return propertyList
-}
-
-func instName(instance *rbxfile.Instance) string {
-	name := instance.Get("Name")
-	if name == nil {
-		return instance.ClassName
-	}
-	var nameStr rbxfile.ValueString
-	var ok bool
-	if nameStr, ok = name.(rbxfile.ValueString); !ok {
-		return instance.ClassName
-	}
-	return string(nameStr)
-}
-
-func getFullName(instance *rbxfile.Instance) string {
-	if instance == nil {
-		return "nil"
-	}
-	parts := make([]string, 0, 8)
-	for instance != nil {
-		parts = append([]string{instName(instance)}, parts...)
-		instance = instance.Parent()
-	}
-	var builder strings.Builder
-	for _, part := range parts {
-		builder.WriteByte('.')
-		builder.WriteString(part)
-	}
-	return builder.String()[1:]
-}
-
-func dumpScripts(location string, instances []*rbxfile.Instance, encountered map[string]int) error {
-	instList := findScripts(instances, nil)
-
-	for _, instProp := range instList {
-		instFullName := getFullName(instProp.Instance)
-		name := fmt.Sprintf("%s/%s.rbxc", location, instFullName)
-		if count, ok := encountered[name]; ok {
-			oldName := name
-			name = fmt.Sprintf("%s/%s.%d.rbxc", location, instFullName, count)
-			encountered[oldName] = count + 1
-		} else {
-			encountered[name] = 1
-		}
-
-		file, err := os.Create(name)
-		if err != nil {
-			return err
-		}
-		_, err = file.Write([]byte(instProp.Instance.Properties[instProp.Name].(datamodel.ValueSignedProtectedString).Value.Value))
-		if err != nil {
-			return err
-		}
-		err = file.Close()
-		if err != nil {
-			return err
-		}
-
-		// HACK: We clear the script source here to prevent issues with the XML parser
-		// This may be unexpected in the case of Studio, where the source code is not protected
-		delete(instProp.Instance.Properties, instProp.Name)
-	}
-	return nil
-}
-
 func DumpDataModel(parent gtk.IWidget, context *peer.CommunicationContext, location string) {
-	writableClone := context.DataModel.ToRbxfile()
-
-	writer, err := os.OpenFile(location+"/datamodel.rbxlx", os.O_RDWR|os.O_CREATE, 0666)
-	defer writer.Close()
+	err := capture.WriteDataModelDump(context, context.DataModel, location)
 	if err != nil {
-		ShowError(parent, err, "Error while creating RBXLX")
-		return
-	}
-
-	scriptData, err := os.OpenFile(location+"/scriptKeys", os.O_RDWR|os.O_CREATE, 0666)
-	defer scriptData.Close()
-	if err != nil {
-		ShowError(parent, err, "Error while dumping script keys")
-		return
-	}
-
-	_, err = fmt.Fprintf(scriptData, "Script key: %d\nCore script key: %d", context.ScriptKey, context.CoreScriptKey)
-	if err != nil {
-		ShowError(parent, err, "Error while dumping script keys")
-		return
-	}
-
-	err = dumpScripts(location, writableClone.Instances, make(map[string]int))
-	if err != nil {
-		ShowError(parent, err, "Error while dumping scripts")
-		return
-	}
-
-	err = xml.Serialize(writer, nil, writableClone)
-	if err != nil {
-		ShowError(parent, err, "Error while serializing place")
-		return
+		ShowError(parent, err, "Error while dumping DataModel")
 	}
 }
 
-func BrowseDataModel(ctx *peer.CommunicationContext) error {
+// BrowseDataModel opens a window that shows the DataModel of the context.
+// The DataModel can be compared with the ones of conversations.
+func BrowseDataModel(ctx *peer.CommunicationContext, conversations func() []*capture.Conversation) error {
 	builder, err := gtk.BuilderNewFromFile("res/instancebrowser.ui")
 	if err != nil {
 		return err
@@ -886,46 +797,58 @@ func BrowseDataModel(ctx *peer.CommunicationContext) error {
 	}
 
 	addInstances(dataTreeModel, nil, ctx.DataModel.Instances)
+	// instances is replaced with the snapshot's instances when
+	// the timeline is scrubbed
+	instances := ctx.InstancesByReference
 
 	sel, err := dataTreeView.GetSelection()
 	if err != nil {
 		return err
 	}
-	sel.Connect("changed", func(sel *gtk.TreeSelection) {
-		model.Clear()
+	// selectedInstance returns the instance selected in the tree,
+	// or nil if there is none
+	selectedInstance := func() *datamodel.Instance {
 		_, iter, ok := sel.GetSelected()
 		if !ok {
-			return
+			return nil
 		}
 
 		scope_, err := dataTreeModel.GetValue(iter, 3)
 		if err != nil {
 			println("error finding instance:", err.Error())
-			return
+			return nil
 		}
 		scope, err := scope_.GoValue()
 		if err != nil {
 			println("error finding instance:", err.Error())
-			return
+			return nil
 		}
 		id_, err := dataTreeModel.GetValue(iter, 4)
 		if err != nil {
 			println("error finding instance:", err.Error())
-			return
+			return nil
 		}
 		id, err := id_.GoValue()
 		if err != nil {
 			println("error finding instance:", err.Error())
-			return
+			return nil
 		}
 
 		ref := datamodel.Reference{
 			Scope: scope.(string),
 			Id:    uint32(id.(uint64)),
 		}
-		instance, err := ctx.InstancesByReference.TryGetInstance(ref)
+		instance, err := instances.TryGetInstance(ref)
 		if err != nil {
 			println("error finding instance:", err.Error())
+			return nil
+		}
+		return instance
+	}
+	sel.Connect("changed", func(sel *gtk.TreeSelection) {
+		model.Clear()
+		instance := selectedInstance()
+		if instance == nil {
 			return
 		}
 		for name, value := range instance.Properties {
@@ -948,13 +871,45 @@ func BrowseDataModel(ctx *peer.CommunicationContext) error {
 	}
 	box.Add(mainWidget)
 
+	// shownModel is the DataModel or snapshot shown in the tree
+	shownModel := ctx.DataModel
+	if ctx.Journal != nil {
+		uniqueIDs := ctx.Journal.UniqueIDs()
+		if len(uniqueIDs) > 1 {
+			timeline, err := gtk.ScaleNewWithRange(gtk.ORIENTATION_HORIZONTAL, 0, float64(len(uniqueIDs)-1), 1)
+			if err != nil {
+				return err
+			}
+			timeline.SetDigits(0)
+			timeline.SetValue(float64(len(uniqueIDs) - 1))
+			timeline.Connect("format-value", func(_ *gtk.Scale, value float64) string {
+				return fmt.Sprintf("Packet %d", uniqueIDs[int(value)])
+			})
+			timeline.Connect("value-changed", func() {
+				index := int(timeline.GetValue())
+				dataModel := ctx.DataModel
+				instances = ctx.InstancesByReference
+				if index != len(uniqueIDs)-1 {
+					dataModel = ctx.Journal.Snapshot(ctx.DataModel, uniqueIDs[index])
+					instances = datamodel.NewInstanceList()
+					instances.Populate(dataModel.Instances)
+				}
+				shownModel = dataModel
+				model.Clear()
+				dataTreeModel.Clear()
+				addInstances(dataTreeModel, nil, dataModel.Instances)
+			})
+			box.Add(timeline)
+		}
+	}
+
 	buttonRow, err := gtk.ButtonBoxNew(gtk.ORIENTATION_HORIZONTAL)
 	if err != nil {
 		return err
 	}
 	buttonRow.SetLayout(gtk.BUTTONBOX_END)
 	buttonRow.SetSpacing(8)
-	loadFromFile, err := gtk.ButtonNewWithLabel("Dump as RBXLX and scripts (RBXC)...")
+	loadFromFile, err := gtk.ButtonNewWithLabel("Dump as RBXL, RBXLX and scripts (RBXC)...")
 	if err != nil {
 		return err
 	}
@@ -973,6 +928,166 @@ func BrowseDataModel(ctx *peer.CommunicationContext) error {
 	})
 	buttonRow.Add(loadFromFile)
 
+	compare, err := gtk.ButtonNewWithLabel("Compare with...")
+	if err != nil {
+		return err
+	}
+	showDiff := func(newModel *datamodel.DataModel) {
+		err := ShowDataModelDiff(shownModel, newModel)
+		if err != nil {
+			ShowError(win, err, "Error while comparing DataModels")
+		}
+	}
+	comparePlace := func(title string, action gtk.FileChooserAction) {
+		chooser, err := gtk.FileChooserNativeDialogNew(title, win, action, "Choose", "Cancel")
+		if err != nil {
+			ShowError(win, err, "Making chooser")
+			return
+		}
+		resp := chooser.NativeDialog.Run()
+		if gtk.ResponseType(resp) != gtk.RESPONSE_ACCEPT {
+			return
+		}
+		root, err := readPlace(chooser.GetFilename())
+		if err != nil {
+			ShowError(win, err, "Error while reading place")
+			return
+		}
+		showDiff(datamodel.FromRbxfile(datamodel.NewInstanceDictionary(1), root))
+	}
+	compare.Connect("clicked", func() {
+		compareMenu, err := gtk.MenuNew()
+		if err != nil {
+			ShowError(win, err, "Making menu")
+			return
+		}
+		addItem := func(label string, activate func()) {
+			item, err := gtk.MenuItemNewWithLabel(label)
+			if err != nil {
+				println("Failed to make menu:", err.Error())
+				return
+			}
+			item.Connect("activate", activate)
+			compareMenu.Append(item)
+		}
+		if ctx.Journal != nil {
+			addItem("End of capture", func() {
+				showDiff(ctx.DataModel)
+			})
+		}
+		for _, conv := range conversations() {
+			if conv.Context == ctx {
+				continue
+			}
+			otherModel := conv.Context.DataModel
+			addItem(fmt.Sprintf("Conversation %s <-> %s", conv.Client, conv.Server), func() {
+				showDiff(otherModel)
+			})
+		}
+		addItem("Place file...", func() {
+			comparePlace("Choose RBXL or RBXLX place", gtk.FILE_CHOOSER_ACTION_OPEN)
+		})
+		addItem("Project folder...", func() {
+			comparePlace("Choose project folder", gtk.FILE_CHOOSER_ACTION_SELECT_FOLDER)
+		})
+		compareMenu.ShowAll()
+		compareMenu.PopupAtPointer(nil)
+	})
+	buttonRow.Add(compare)
+
+	exportProject, err := gtk.ButtonNewWithLabel("Export as project folder...")
+	if err != nil {
+		return err
+	}
+	exportProject.Connect("clicked", func() {
+		chooser, err := gtk.FileChooserNativeDialogNew("Choose empty folder", win, gtk.FILE_CHOOSER_ACTION_CREATE_FOLDER, "Choose", "Cancel")
+		if err != nil {
+			ShowError(win, err, "Making chooser")
+			return
+		}
+
+		resp := chooser.NativeDialog.Run()
+		if gtk.ResponseType(resp) == gtk.RESPONSE_ACCEPT {
+			err = ctx.DataModel.WriteProject(chooser.GetFilename())
+			if err != nil {
+				ShowError(win, err, "Error while exporting project")
+			}
+		}
+	})
+	buttonRow.Add(exportProject)
+
+	exportModel, err := gtk.ButtonNewWithLabel("Export selected as RBXM...")
+	if err != nil {
+		return err
+	}
+	exportModel.Connect("clicked", func() {
+		instance := selectedInstance()
+		if instance == nil {
+			return
+		}
+		chooser, err := gtk.FileChooserNativeDialogNew("Save model", win, gtk.FILE_CHOOSER_ACTION_SAVE, "Save", "Cancel")
+		if err != nil {
+			ShowError(win, err, "Making chooser")
+			return
+		}
+		chooser.SetCurrentName(instance.Name() + ".rbxm")
+
+		resp := chooser.NativeDialog.Run()
+		if gtk.ResponseType(resp) != gtk.RESPONSE_ACCEPT {
+			return
+		}
+		file, err := os.Create(chooser.GetFilename())
+		if err != nil {
+			ShowError(win, err, "Error while creating RBXM")
+			return
+		}
+		defer file.Close()
+		err = instance.WriteRbxm(file)
+		if err != nil {
+			ShowError(win, err, "Error while serializing model")
+		}
+	})
+	buttonRow.Add(exportModel)
+
+	if ctx.DataModel.History != nil {
+		exportHistory, err := gtk.ButtonNewWithLabel("Export property history as CSV...")
+		if err != nil {
+			return err
+		}
+		exportHistory.Connect("clicked", func() {
+			instance := selectedInstance()
+			if instance == nil {
+				return
+			}
+			// The history is kept for the live instances, not the snapshot copies
+			if live, err := ctx.InstancesByReference.TryGetInstance(instance.Ref); err == nil {
+				instance = live
+			}
+			chooser, err := gtk.FileChooserNativeDialogNew("Save property history", win, gtk.FILE_CHOOSER_ACTION_SAVE, "Save", "Cancel")
+			if err != nil {
+				ShowError(win, err, "Making chooser")
+				return
+			}
+			chooser.SetCurrentName(instance.Name() + ".csv")
+
+			resp := chooser.NativeDialog.Run()
+			if gtk.ResponseType(resp) != gtk.RESPONSE_ACCEPT {
+				return
+			}
+			file, err := os.Create(chooser.GetFilename())
+			if err != nil {
+				ShowError(win, err, "Error while creating CSV")
+				return
+			}
+			defer file.Close()
+			err = ctx.DataModel.History.WriteCSV(file, instance)
+			if err != nil {
+				ShowError(win, err, "Error while writing CSV")
+			}
+		})
+		buttonRow.Add(exportHistory)
+	}
+
 	okButton, err := gtk.ButtonNewWithLabel("OK")
 	if err != nil {
 		return err
@@ -1,23 +1,20 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/Gskartwii/roblox-dissector/util/capture"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/robloxapi/rbxapi/rbxapijson"
-	"github.com/robloxapi/rbxapiref/fetch"
 )
 
-// Can't use https:// because the site is broken
-const CDNURL = "http://setup.roblox.com/"
-
 var latestRobloxAPI *rbxapijson.Root
 var latestRobloxAPIChan chan struct{} // Closed when retrievement is done
 
@@ -27,22 +24,7 @@ func init() {
 		defer func() {
 			close(latestRobloxAPIChan)
 		}()
-		robloxApiClient := &fetch.Client{
-			Client: &http.Client{},
-			Config: fetch.Config{
-				Builds:             []fetch.Location{fetch.NewLocation(CDNURL + "DeployHistory.txt")},
-				Latest:             []fetch.Location{fetch.NewLocation(CDNURL + "versionQTStudio")},
-				APIDump:            []fetch.Location{fetch.NewLocation(CDNURL + "$HASH-API-Dump.json")},
-				ReflectionMetadata: []fetch.Location{fetch.NewLocation(CDNURL + "$HASH-RobloxStudio.zip#ReflectionMetadata.xml")},
-				ExplorerIcons:      []fetch.Location{fetch.NewLocation(CDNURL + "$HASH-RobloxStudio.zip#RobloxStudioBeta.exe")},
-			},
-		}
-		latestBuild, err := robloxApiClient.Latest()
-		if err != nil {
-			fmt.Println("Error retrieving API:", err.Error())
-			return
-		}
-		apiDump, err := robloxApiClient.APIDump(latestBuild.Hash)
+		apiDump, err := capture.FetchLatestAPI()
 		if err != nil {
 			fmt.Println("Error retrieving API:", err.Error())
 			return
@@ -1014,6 +996,14 @@ func schemaViewer(packet *peer.Packet97Layer) (gtk.IWidget, error) {
 		filter.AddPattern("*.txt")
 		filter.SetName("TXT files (*.txt)")
 		dialog.AddFilter(filter)
+		jsonFilter, err := gtk.FileFilterNew()
+		if err != nil {
+			println("Failed to make filter:", err.Error())
+			return
+		}
+		jsonFilter.AddPattern("*.json")
+		jsonFilter.SetName("JSON files (*.json)")
+		dialog.AddFilter(jsonFilter)
 		resp := dialog.Run()
 		if gtk.ResponseType(resp) == gtk.RESPONSE_ACCEPT {
 			filename := dialog.GetFilename()
@@ -1022,7 +1012,12 @@ func schemaViewer(packet *peer.Packet97Layer) (gtk.IWidget, error) {
 				ShowError(box, err, "Saving schema to file")
 				return
 			}
-			err = packet.Schema.Dump(schemaFile)
+			defer schemaFile.Close()
+			if strings.HasSuffix(filename, ".json") {
+				err = packet.Schema.DumpJSON(schemaFile)
+			} else {
+				err = packet.Schema.Dump(schemaFile)
+			}
 			if err != nil {
 				ShowError(box, err, "Saving schema to file")
 			}
@@ -1032,6 +1027,31 @@ func schemaViewer(packet *peer.Packet97Layer) (gtk.IWidget, error) {
 	dumpSchemaBtn.SetHAlign(gtk.ALIGN_START)
 	box.Add(dumpSchemaBtn)
 
+	checkAPIBtn, err := gtk.ButtonNewWithLabel("Check against API dump...")
+	if err != nil {
+		return nil, err
+	}
+	checkAPIBtn.Connect("clicked", func() {
+		if latestRobloxAPI == nil {
+			ShowError(box, errors.New("API dump is not available"), "Checking schema against API dump")
+			return
+		}
+		logWindow, err := NewFilterLogWindow("Members missing from API dump")
+		if err != nil {
+			ShowError(box, err, "Checking schema against API dump")
+			return
+		}
+		missing := packet.Schema.MembersMissingFromAPI(latestRobloxAPI)
+		logWindow.AppendLog(fmt.Sprintf("%d replicated members are missing from the API dump\n", len(missing)))
+		for _, member := range missing {
+			logWindow.AppendLog(member.String() + "\n")
+		}
+		logWindow.Show()
+	})
+	checkAPIBtn.SetHExpand(false)
+	checkAPIBtn.SetHAlign(gtk.ALIGN_START)
+	box.Add(checkAPIBtn)
+
 	box.ShowAll()
 	return box, nil
 }
@@ -1072,8 +1092,12 @@ func luauChallengeViewer(packet *peer.Packet9BLayer) (gtk.IWidget, error) {
 }
 
 func viewerForMainPacket(packet peer.RakNetPacket) (gtk.IWidget, error) {
+	// Unknown packets are shown in the hex dump
+	if _, ok := packet.(*peer.OpaquePacket); ok {
+		return blanketViewer(packet.String())
+	}
 	switch packet.Type() {
-	case 0x00, 0x03, 0x83, 0x84, 0x85, 0x86, 0x87, 0x8F, 0x92, 0x96, 0x98:
+	case 0x00, 0x03, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x8B, 0x8C, 0x8F, 0x92, 0x96, 0x98:
 		return blanketViewer(packet.String())
 	case 0x05:
 		return openConnectionReq1Viewer(packet.(*peer.Packet05Layer))
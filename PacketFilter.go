@@ -113,18 +113,16 @@ func replicationInstanceReferences(repInst *peer.ReplicationInstance, ref datamo
 }
 
 func referencesInstance(packet peer.Packet83Subpacket, ref datamodel.Reference) uint {
-	switch packet.Type() {
-	case 0x01:
-		deletion := packet.(*peer.Packet83_01)
-		deletedRef := deletion.Instance.Ref
+	switch packet := packet.(type) {
+	case *peer.Packet83_01:
+		deletedRef := packet.Instance.Ref
 		if deletedRef.Equal(&ref) {
 			return InstanceDeletion
 		}
-	case 0x02:
-		creation := packet.(*peer.Packet83_02)
-		return replicationInstanceReferences(creation.ReplicationInstance, ref)
-	case 0x03:
-		propSet := packet.(*peer.Packet83_03)
+	case *peer.Packet83_02:
+		return replicationInstanceReferences(packet.ReplicationInstance, ref)
+	case *peer.Packet83_03:
+		propSet := packet
 		propRef := propSet.Instance.Ref
 		var refType uint
 		if propRef.Equal(&ref) {
@@ -134,8 +132,8 @@ func referencesInstance(packet peer.Packet83Subpacket, ref datamodel.Reference)
 			refType |= InstanceInValue
 		}
 		return refType
-	case 0x07:
-		event := packet.(*peer.Packet83_07)
+	case *peer.Packet83_07:
+		event := packet
 		eventRef := event.Instance.Ref
 		var refType uint
 		if eventRef.Equal(&ref) {
@@ -148,16 +146,14 @@ func referencesInstance(packet peer.Packet83Subpacket, ref datamodel.Reference)
 			}
 		}
 		return refType
-	case 0x0A:
-		ack := packet.(*peer.Packet83_0A)
-		ackRef := ack.Instance.Ref
+	case *peer.Packet83_0A:
+		ackRef := packet.Instance.Ref
 		if ackRef.Equal(&ref) {
 			return InstanceAck
 		}
-	case 0x0B:
-		joinData := packet.(*peer.Packet83_0B)
+	case *peer.Packet83_0B:
 		var refType uint
-		for _, inst := range joinData.Instances {
+		for _, inst := range packet.Instances {
 			refType |= replicationInstanceReferences(inst, ref)
 		}
 		return refType
@@ -186,25 +182,22 @@ func physicsDataRefs(physicsData *peer.PhysicsData, ref datamodel.Reference) uin
 func packetInstanceReferenceDataMask(packet peer.RakNetPacket, ref datamodel.Reference) uint {
 	var referenceData uint
 
-	switch packet.Type() {
-	case 0x81:
-		topReplic := packet.(*peer.Packet81Layer)
-		for _, inst := range topReplic.Items {
+	switch packet := packet.(type) {
+	case *peer.Packet81Layer:
+		for _, inst := range packet.Items {
 			topRef := inst.Instance.Ref
 			if topRef.Equal(&ref) {
 				referenceData |= InstanceTopReplicated
 			}
 		}
-	case 0x83:
-		dataPacket := packet.(*peer.Packet83Layer)
-		for _, subpacket := range dataPacket.SubPackets {
+	case *peer.Packet83Layer:
+		for _, subpacket := range packet.SubPackets {
 			if refType := referencesInstance(subpacket, ref); refType != NotReferenced {
 				referenceData |= refType
 			}
 		}
-	case 0x85:
-		physics := packet.(*peer.Packet85Layer)
-		for _, subpacket := range physics.SubPackets {
+	case *peer.Packet85Layer:
+		for _, subpacket := range packet.SubPackets {
 			referenceData |= physicsDataRefs(&subpacket.Data, ref)
 			for _, child := range subpacket.Children {
 				subRefData := physicsDataRefs(child, ref)
@@ -218,9 +211,8 @@ func packetInstanceReferenceDataMask(packet peer.RakNetPacket, ref datamodel.Ref
 				referenceData |= physicsDataRefs(history, ref)
 			}
 		}
-	case 0x86:
-		touches := packet.(*peer.Packet86Layer)
-		for _, subpacket := range touches.SubPackets {
+	case *peer.Packet86Layer:
+		for _, subpacket := range packet.SubPackets {
 			if subpacket.Instance1.Ref.Equal(&ref) || subpacket.Instance2.Ref.Equal(&ref) {
 				if subpacket.IsTouch {
 					referenceData |= InstanceTouched
@@ -263,11 +255,12 @@ var packetMethods = map[string]lua.LGFunction{
 		packet := checkPacket(L)
 		typ := uint8(L.CheckInt(2))
 
-		if packet.Type() != 0x83 {
+		dataPacket, ok := packet.(*peer.Packet83Layer)
+		if !ok {
 			L.Push(lua.LBool(false))
 			return 1
 		}
-		for _, sub := range packet.(*peer.Packet83Layer).SubPackets {
+		for _, sub := range dataPacket.SubPackets {
 			if sub.Type() == typ {
 				L.Push(lua.LBool(true))
 				return 1
@@ -310,6 +303,57 @@ var replicPacketMethods = map[string]lua.LGFunction{
 		L.Push(lua.LBool(packet.Type() == typ))
 		return 1
 	},
+	"PropertyName": func(L *lua.LState) int {
+		packet, ok := checkPacket(L).(*peer.Packet83_03)
+		if !ok {
+			L.Push(lua.LNil)
+			return 1
+		}
+		if packet.Schema == nil {
+			L.Push(lua.LString("Parent"))
+		} else {
+			L.Push(lua.LString(packet.Schema.Name))
+		}
+		return 1
+	},
+	"PropertyValue": func(L *lua.LState) int {
+		packet, ok := checkPacket(L).(*peer.Packet83_03)
+		if !ok {
+			L.Push(lua.LNil)
+			return 1
+		}
+		L.Push(bridgeValue(packet.Value))
+		return 1
+	},
+}
+
+// bridgeValue converts simple values to their Lua counterparts.
+// Enum values are converted to their item names if they are known.
+// Other values are converted to strings.
+func bridgeValue(val rbxfile.Value) lua.LValue {
+	switch val := val.(type) {
+	case nil:
+		return lua.LNil
+	case rbxfile.ValueBool:
+		return lua.LBool(val)
+	case rbxfile.ValueInt:
+		return lua.LNumber(val)
+	case rbxfile.ValueInt64:
+		return lua.LNumber(val)
+	case rbxfile.ValueFloat:
+		return lua.LNumber(val)
+	case rbxfile.ValueDouble:
+		return lua.LNumber(val)
+	case rbxfile.ValueString:
+		return lua.LString(val)
+	case datamodel.ValueToken:
+		if val.ItemName != "" {
+			return lua.LString(val.ItemName)
+		}
+		return lua.LNumber(val.Value)
+	default:
+		return lua.LString(val.String())
+	}
 }
 
 func checkPacket(L *lua.LState) peer.RakNetPacket {
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 
+	"github.com/Gskartwii/roblox-dissector/datamodel"
 	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/Gskartwii/roblox-dissector/util/capture"
 	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
@@ -33,7 +35,7 @@ const (
 )
 
 type PacketListViewer struct {
-	Conversation *Conversation
+	Conversation *capture.Conversation
 
 	updatePassthrough bool
 	queuedChannels    []string
@@ -89,7 +91,7 @@ func ShowPacketListViewerWindow(title string, forPacket *peer.PacketLayers) erro
 	return nil
 }
 
-func NewPacketListViewer(title string, conversation *Conversation) (*PacketListViewer, error) {
+func NewPacketListViewer(title string, conversation *capture.Conversation) (*PacketListViewer, error) {
 	viewer := &PacketListViewer{
 		Conversation:      conversation,
 		packetRows:        make(map[uint64]*gtk.TreePath),
@@ -486,9 +488,9 @@ func (viewer *PacketListViewer) appendPartialPacketRow(layers *peer.PacketLayers
 
 func (viewer *PacketListViewer) addSubpackets(iter *gtk.TreeIter, layers *peer.PacketLayers) {
 	model := viewer.model
-	switch layers.PacketType {
-	case 0x83:
-		mainLayer := layers.Main.(*peer.Packet83Layer)
+	// Subpacket rows are only created for the built-in packet types
+	switch mainLayer := layers.Main.(type) {
+	case *peer.Packet83Layer:
 		for index, subpacket := range mainLayer.SubPackets {
 			var newRow gtk.TreeIter
 			err := model.InsertWithValues(&newRow, iter, -1, []int{
@@ -546,8 +548,7 @@ func (viewer *PacketListViewer) addSubpackets(iter *gtk.TreeIter, layers *peer.P
 				}
 			}
 		}
-	case 0x85:
-		mainLayer := layers.Main.(*peer.Packet85Layer)
+	case *peer.Packet85Layer:
 		for index, subpacket := range mainLayer.SubPackets {
 			newRow := model.Append(iter)
 			model.SetValue(newRow, COL_ID, int64(index))
@@ -556,8 +557,7 @@ func (viewer *PacketListViewer) addSubpackets(iter *gtk.TreeIter, layers *peer.P
 			model.SetValue(newRow, COL_HAS_LENGTH, false)
 			model.SetValue(newRow, COL_PACKET_KIND, int64(KIND_PHYSICS))
 		}
-	case 0x86:
-		mainLayer := layers.Main.(*peer.Packet86Layer)
+	case *peer.Packet86Layer:
 		for index, subpacket := range mainLayer.SubPackets {
 			newRow := model.Append(iter)
 			model.SetValue(newRow, COL_ID, int64(index))
@@ -573,19 +573,16 @@ func (viewer *PacketListViewer) addLazySubpackets(iter *gtk.TreeIter, layers *pe
 	model := viewer.model
 
 	var lazyIter *gtk.TreeIter
-	switch layers.PacketType {
-	case 0x83:
-		mainLayer := layers.Main.(*peer.Packet83Layer)
+	switch mainLayer := layers.Main.(type) {
+	case *peer.Packet83Layer:
 		if len(mainLayer.SubPackets) > 0 {
 			lazyIter = model.Append(iter)
 		}
-	case 0x85:
-		mainLayer := layers.Main.(*peer.Packet85Layer)
+	case *peer.Packet85Layer:
 		if len(mainLayer.SubPackets) > 0 {
 			lazyIter = model.Append(iter)
 		}
-	case 0x86:
-		mainLayer := layers.Main.(*peer.Packet86Layer)
+	case *peer.Packet86Layer:
 		if len(mainLayer.SubPackets) > 0 {
 			lazyIter = model.Append(iter)
 		}
@@ -758,7 +755,11 @@ func (viewer *PacketListViewer) selectionChanged(selection *gtk.TreeSelection) {
 
 		switch kind {
 		case KIND_DATA_REPLIC:
-			packetViewer, err := viewerForDataPacket(viewer.packetStore[mainPacketId].Main.(*peer.Packet83Layer).SubPackets[baseId])
+			var history *datamodel.PropertyHistory
+			if viewer.Conversation != nil {
+				history = viewer.Conversation.Context.DataModel.History
+			}
+			packetViewer, err := viewerForDataPacket(viewer.packetStore[mainPacketId].Main.(*peer.Packet83Layer).SubPackets[baseId], history)
 			if err != nil {
 				println("failed to get subpacket viewer:", err.Error())
 				return
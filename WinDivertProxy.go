@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/Gskartwii/roblox-dissector/util/capture"
 
 	windivert "github.com/Gskartwii/windivert-go"
 	"github.com/olebedev/emitter"
@@ -59,11 +60,11 @@ func CaptureWithDivertedPacket(ctx context.Context, session *CaptureSession, cli
 		}
 	}, emitter.Void)
 
-	clientConversation := &Conversation{
+	clientConversation := &capture.Conversation{
 		ClientReader: proxyWriter.ClientHalf.DefaultPacketWriter,
 		ServerReader: proxyWriter.ClientHalf.DefaultPacketReader,
 	}
-	serverConversation := &Conversation{
+	serverConversation := &capture.Conversation{
 		ClientReader: proxyWriter.ServerHalf.DefaultPacketReader,
 		ServerReader: proxyWriter.ServerHalf.DefaultPacketWriter,
 	}
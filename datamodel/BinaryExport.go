@@ -0,0 +1,88 @@
+package datamodel
+
+import (
+	"io"
+
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/bin"
+	"github.com/robloxapi/rbxfile/xml"
+)
+
+// prepareScripts converts signed script bytecode, which rbxfile can't
+// represent, using convert
+func prepareScripts(instances []*rbxfile.Instance, convert func([]byte) rbxfile.Value) {
+	for _, inst := range instances {
+		for name, value := range inst.Properties {
+			var deferred *ValueDeferredString
+			switch value := value.(type) {
+			case ValueSignedProtectedString:
+				deferred = value.Value
+			case *ValueSignedProtectedString:
+				deferred = value.Value
+			default:
+				continue
+			}
+			if deferred == nil {
+				delete(inst.Properties, name)
+				continue
+			}
+			inst.Properties[name] = convert([]byte(deferred.Value))
+		}
+		prepareScripts(inst.Children, convert)
+	}
+}
+
+// prepareBinary converts the values that the binary format can't represent
+// directly. Signed script bytecode is kept as a ProtectedString blob.
+func prepareBinary(instances []*rbxfile.Instance) {
+	prepareScripts(instances, func(bytecode []byte) rbxfile.Value {
+		return rbxfile.ValueProtectedString(bytecode)
+	})
+}
+
+// prepareXML converts the values that the XML format can't represent
+// directly. Signed script bytecode isn't valid XML text, so it is
+// kept as a BinaryString, which is encoded in base64. SharedStrings are
+// converted too, because the XML encoder of rbxfile doesn't write the
+// SharedStrings section that they must refer to.
+func prepareXML(instances []*rbxfile.Instance) {
+	prepareScripts(instances, func(bytecode []byte) rbxfile.Value {
+		return rbxfile.ValueBinaryString(bytecode)
+	})
+	prepareSharedStrings(instances)
+}
+
+func prepareSharedStrings(instances []*rbxfile.Instance) {
+	for _, inst := range instances {
+		for name, value := range inst.Properties {
+			if value, ok := value.(rbxfile.ValueSharedString); ok {
+				inst.Properties[name] = rbxfile.ValueBinaryString(value)
+			}
+		}
+		prepareSharedStrings(inst.Children)
+	}
+}
+
+// WriteRbxl writes the DataModel to w as a binary place file
+func (model *DataModel) WriteRbxl(w io.Writer) error {
+	root := model.ToRbxfile()
+	prepareBinary(root.Instances)
+	return bin.SerializePlace(w, nil, root)
+}
+
+// WriteRbxlx writes the DataModel to w as an XML place file
+func (model *DataModel) WriteRbxlx(w io.Writer) error {
+	root := model.ToRbxfile()
+	prepareXML(root.Instances)
+	return xml.Serialize(w, nil, root)
+}
+
+// WriteRbxm writes the instance and its descendants to w as a binary model file.
+// References to instances outside of the subtree are written as nil.
+func (instance *Instance) WriteRbxm(w io.Writer) error {
+	root := &rbxfile.Root{
+		Instances: []*rbxfile.Instance{instance.ToRbxfile(NewRbxfileReferencePool())},
+	}
+	prepareBinary(root.Instances)
+	return bin.SerializeModel(w, nil, root)
+}
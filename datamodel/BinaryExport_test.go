@@ -0,0 +1,115 @@
+package datamodel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/bin"
+	"github.com/robloxapi/rbxfile/xml"
+)
+
+var testBytecode = []byte("\x03\x01\x04\x00bytecode\xff\xfe")
+
+// exportTestModel returns a DataModel containing a script and a SharedString
+func exportTestModel() (*DataModel, *Instance) {
+	model := New()
+	workspace, _ := NewInstance("Workspace", nil)
+	workspace.IsService = true
+	model.AddService(workspace)
+
+	folder, _ := NewInstance("Folder", workspace)
+	folder.Set("Name", rbxfile.ValueString("Folder"))
+	part, _ := NewInstance("Part", folder)
+	part.Set("Name", rbxfile.ValueString("Part"))
+	part.Set("PhysicsData", &ValueDeferredString{Value: rbxfile.ValueSharedString("shared")})
+	script, _ := NewInstance("Script", folder)
+	script.Set("Name", rbxfile.ValueString("Script"))
+	script.Set("Source", ValueSignedProtectedString{
+		Signature: []byte{1, 2, 3},
+		Value:     &ValueDeferredString{Value: rbxfile.ValueSharedString(testBytecode)},
+	})
+	return model, folder
+}
+
+// exportedBytes returns the contents of a string-like value
+func exportedBytes(value rbxfile.Value) (string, bool) {
+	switch value := value.(type) {
+	case rbxfile.ValueString:
+		return string(value), true
+	case rbxfile.ValueProtectedString:
+		return string(value), true
+	case rbxfile.ValueBinaryString:
+		return string(value), true
+	case rbxfile.ValueSharedString:
+		return string(value), true
+	}
+	return "", false
+}
+
+// checkExport checks that the folder contains the exported part and script
+func checkExport(t *testing.T, format string, folder *rbxfile.Instance) {
+	t.Helper()
+	if folder == nil || folder.ClassName != "Folder" || len(folder.Children) != 2 {
+		t.Fatalf("%s: folder wasn't exported: %v", format, folder)
+	}
+	part, script := folder.Children[0], folder.Children[1]
+	if shared, ok := exportedBytes(part.Get("PhysicsData")); !ok || shared != "shared" {
+		t.Errorf("%s: SharedString wasn't exported: %#v", format, part.Get("PhysicsData"))
+	}
+	// The type of the bytecode can't be decoded without an API dump
+	if source, ok := exportedBytes(script.Get("Source")); !ok || source != string(testBytecode) {
+		t.Errorf("%s: script bytecode wasn't exported: %#v", format, script.Get("Source"))
+	}
+}
+
+func TestWriteRbxl(t *testing.T) {
+	model, _ := exportTestModel()
+	var place bytes.Buffer
+	err := model.WriteRbxl(&place)
+	if err != nil {
+		t.Fatalf("failed to write place: %s", err.Error())
+	}
+	root, err := bin.DeserializePlace(&place, nil)
+	if err != nil {
+		t.Fatalf("failed to read place: %s", err.Error())
+	}
+	if len(root.Instances) != 1 || len(root.Instances[0].Children) != 1 {
+		t.Fatalf("unexpected instances in place: %v", root.Instances)
+	}
+	checkExport(t, "RBXL", root.Instances[0].Children[0])
+}
+
+func TestWriteRbxlx(t *testing.T) {
+	model, _ := exportTestModel()
+	var place bytes.Buffer
+	err := model.WriteRbxlx(&place)
+	if err != nil {
+		t.Fatalf("failed to write place: %s", err.Error())
+	}
+	root, err := xml.Deserialize(&place, nil)
+	if err != nil {
+		t.Fatalf("failed to read place: %s", err.Error())
+	}
+	if len(root.Instances) != 1 || len(root.Instances[0].Children) != 1 {
+		t.Fatalf("unexpected instances in place: %v", root.Instances)
+	}
+	checkExport(t, "RBXLX", root.Instances[0].Children[0])
+}
+
+func TestWriteRbxm(t *testing.T) {
+	_, folder := exportTestModel()
+	var rbxm bytes.Buffer
+	err := folder.WriteRbxm(&rbxm)
+	if err != nil {
+		t.Fatalf("failed to write model: %s", err.Error())
+	}
+	root, err := bin.DeserializeModel(&rbxm, nil)
+	if err != nil {
+		t.Fatalf("failed to read model: %s", err.Error())
+	}
+	if len(root.Instances) != 1 {
+		t.Fatalf("unexpected instances in model: %v", root.Instances)
+	}
+	checkExport(t, "RBXM", root.Instances[0])
+}
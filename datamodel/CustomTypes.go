@@ -2,6 +2,7 @@ package datamodel
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 
 	"github.com/robloxapi/rbxfile"
@@ -26,11 +27,20 @@ func (ref Reference) String() string {
 	return fmt.Sprintf("%s_%d", ref.Scope, ref.Id)
 }
 
+// Equal returns whether the references refer to the same instance.
+// References are compared by peer ID if both of them carry one,
+// and by scope otherwise.
 func (ref Reference) Equal(other *Reference) bool {
 	if ref.IsNull {
 		return other.IsNull
 	}
-	return ref.Id == other.Id && ref.PeerId == other.PeerId
+	if other.IsNull || ref.Id != other.Id {
+		return false
+	}
+	if ref.PeerId != 0 && other.PeerId != 0 {
+		return ref.PeerId == other.PeerId
+	}
+	return ref.Scope == other.Scope
 }
 
 const (
@@ -104,6 +114,11 @@ type ValuePathWaypoint struct {
 type ValueToken struct {
 	ID    uint16
 	Value uint32
+	// EnumName and ItemName are the names of the enum and the enum item,
+	// if they are known. They are purely informational and are not
+	// used for serialization.
+	EnumName string
+	ItemName string
 }
 
 type ValueVector3int32 struct {
@@ -252,6 +267,12 @@ func (x ValueReference) Copy() rbxfile.Value {
 func (x ValueReference) Type() rbxfile.Type {
 	return TypeReference
 }
+
+// IsUnresolved returns whether the reference points to an instance
+// that didn't exist when the value was read
+func (x ValueReference) IsUnresolved() bool {
+	return x.Instance == nil && !x.Reference.IsNull
+}
 func (x ValueReference) String() string {
 	return fmt.Sprintf("%s: %s", x.Reference.String(), x.Instance.GetFullName())
 }
@@ -317,8 +338,18 @@ func (x ValueToken) Copy() rbxfile.Value {
 	return x
 }
 func (x ValueToken) String() string {
+	if x.EnumName != "" && x.ItemName != "" {
+		return fmt.Sprintf("Enum.%s.%s", x.EnumName, x.ItemName)
+	}
 	return rbxfile.ValueToken(x.Value).String()
 }
+func (x ValueToken) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Enum  string `json:"enum,omitempty"`
+		Item  string `json:"item,omitempty"`
+		Value uint32 `json:"value"`
+	}{x.EnumName, x.ItemName, x.Value})
+}
 
 func (x ValueVector3int32) Type() rbxfile.Type {
 	return TypeVector3int32
@@ -2,6 +2,7 @@ package datamodel
 
 import (
 	"context"
+	"strings"
 
 	"github.com/olebedev/emitter"
 )
@@ -9,6 +10,9 @@ import (
 type DataModel struct {
 	Instances      []*Instance
 	ServiceEmitter *emitter.Emitter
+	// History records the values that properties have taken.
+	// If it is nil, no history is kept.
+	History *PropertyHistory
 }
 
 func New() *DataModel {
@@ -31,6 +35,20 @@ func (model *DataModel) FindService(name string) *Instance {
 	return nil
 }
 
+// FindInstance finds an instance by a path such as "ReplicatedStorage.Remotes.Event",
+// which begins with the class name of a service. It returns nil if it isn't found.
+func (model *DataModel) FindInstance(path string) *Instance {
+	names := strings.Split(path, ".")
+	instance := model.FindService(names[0])
+	for _, name := range names[1:] {
+		if instance == nil {
+			return nil
+		}
+		instance = instance.FindFirstChild(name)
+	}
+	return instance
+}
+
 func (model *DataModel) WaitForService(ctx context.Context, name string) (*Instance, error) {
 	service := model.FindService(name)
 	if service != nil {
@@ -0,0 +1,422 @@
+package datamodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+// PropertyDiff describes a property whose value differs between two instances.
+// Old or New is nil if the property only exists in one of them.
+type PropertyDiff struct {
+	Name string
+	Old  rbxfile.Value
+	New  rbxfile.Value
+}
+
+// InstanceDiff describes an instance that exists in both DataModels
+// but was reparented or had its properties changed
+type InstanceDiff struct {
+	Old *Instance
+	New *Instance
+	// Reparented is true if the instance's parent doesn't correspond to
+	// its old parent
+	Reparented bool
+	Properties []PropertyDiff
+}
+
+// DataModelDiff is the result of comparing two DataModels
+type DataModelDiff struct {
+	// Added contains the instances that only exist in the new DataModel.
+	// Descendants of added instances are not listed separately.
+	Added []*Instance
+	// Removed contains the instances that only exist in the old DataModel.
+	// Descendants of removed instances are not listed separately.
+	Removed []*Instance
+	// Changed contains the reparented instances and the instances
+	// whose properties changed
+	Changed []*InstanceDiff
+}
+
+// maxDiffComparisons is the maximum number of similarity comparisons
+// made for instances with the same name and class. Larger groups
+// are matched in order.
+const maxDiffComparisons = 0x10000
+
+type differ struct {
+	// matches maps instances in the old DataModel to instances in the new one
+	matches  map[*Instance]*Instance
+	matchedB map[*Instance]bool
+	// pairs contains the matched instances in the order they were matched
+	pairs [][2]*Instance
+}
+
+func diffKey(inst *Instance) string {
+	return inst.Name() + "\x00" + inst.ClassName
+}
+
+// similarity counts the properties that are equal in the two instances
+func (d *differ) similarity(a, b *Instance) int {
+	a.PropertiesMutex.RLock()
+	defer a.PropertiesMutex.RUnlock()
+	b.PropertiesMutex.RLock()
+	defer b.PropertiesMutex.RUnlock()
+	count := 0
+	for name, value := range a.Properties {
+		if otherValue, ok := b.Properties[name]; ok && d.valuesEqual(value, otherValue) {
+			count++
+		}
+	}
+	return count
+}
+
+// diffParent returns the parent of the instance, or nil if the
+// instance is a service
+func diffParent(inst *Instance) *Instance {
+	parent := inst.Parent()
+	// FromRbxfile() parents services to a dummy root
+	if parent != nil && parent.ClassName == "DataModel" {
+		return nil
+	}
+	return parent
+}
+
+func (d *differ) match(a, b *Instance) {
+	d.matches[a] = b
+	d.matchedB[b] = true
+	d.pairs = append(d.pairs, [2]*Instance{a, b})
+}
+
+// pairCandidates matches instances with the same name and class.
+// If several instances share the same name and class, the most similar
+// ones are paired first. The matched pairs are returned in order.
+func (d *differ) pairCandidates(as, bs []*Instance) [][2]*Instance {
+	groupsB := make(map[string][]*Instance)
+	for _, b := range bs {
+		if !d.matchedB[b] {
+			key := diffKey(b)
+			groupsB[key] = append(groupsB[key], b)
+		}
+	}
+	groupsA := make(map[string][]*Instance)
+	var keys []string
+	for _, a := range as {
+		if _, ok := d.matches[a]; ok {
+			continue
+		}
+		key := diffKey(a)
+		if _, ok := groupsB[key]; !ok {
+			continue
+		}
+		if _, ok := groupsA[key]; !ok {
+			keys = append(keys, key)
+		}
+		groupsA[key] = append(groupsA[key], a)
+	}
+
+	var pairs [][2]*Instance
+	for _, key := range keys {
+		candidatesA, candidatesB := groupsA[key], groupsB[key]
+		if len(candidatesA) == 1 && len(candidatesB) == 1 || len(candidatesA)*len(candidatesB) > maxDiffComparisons {
+			// Pair the instances in order
+			for i := 0; i < len(candidatesA) && i < len(candidatesB); i++ {
+				d.match(candidatesA[i], candidatesB[i])
+				pairs = append(pairs, [2]*Instance{candidatesA[i], candidatesB[i]})
+			}
+			continue
+		}
+		type scoredPair struct {
+			a, b  int
+			score int
+		}
+		var scored []scoredPair
+		for i, a := range candidatesA {
+			for j, b := range candidatesB {
+				scored = append(scored, scoredPair{i, j, d.similarity(a, b)})
+			}
+		}
+		// Prefer the original order among equally similar pairs
+		sort.SliceStable(scored, func(i, j int) bool {
+			return scored[i].score > scored[j].score
+		})
+		usedA := make(map[int]bool)
+		usedB := make(map[int]bool)
+		for _, pair := range scored {
+			if usedA[pair.a] || usedB[pair.b] {
+				continue
+			}
+			usedA[pair.a] = true
+			usedB[pair.b] = true
+			d.match(candidatesA[pair.a], candidatesB[pair.b])
+			pairs = append(pairs, [2]*Instance{candidatesA[pair.a], candidatesB[pair.b]})
+		}
+	}
+	return pairs
+}
+
+func (d *differ) matchChildren(as, bs []*Instance) {
+	for _, pair := range d.pairCandidates(as, bs) {
+		d.matchChildren(pair[0].Children, pair[1].Children)
+	}
+}
+
+func collectUnmatched(instances []*Instance, isMatched func(*Instance) bool, unmatched []*Instance) []*Instance {
+	for _, inst := range instances {
+		if !isMatched(inst) {
+			unmatched = append(unmatched, inst)
+		}
+		unmatched = collectUnmatched(inst.Children, isMatched, unmatched)
+	}
+	return unmatched
+}
+
+// valuesEqual compares two property values. References are equal if they
+// point to matched instances and tokens are equal if their values are equal,
+// regardless of the enum metadata attached to them.
+func (d *differ) valuesEqual(a, b rbxfile.Value) bool {
+	switch a := a.(type) {
+	case ValueReference:
+		b, ok := b.(ValueReference)
+		if !ok {
+			return false
+		}
+		if a.Instance == nil || b.Instance == nil {
+			return a.Instance == nil && b.Instance == nil
+		}
+		return d.matches[a.Instance] == b.Instance
+	case ValueToken:
+		b, ok := b.(ValueToken)
+		return ok && a.Value == b.Value
+	case ValueTuple:
+		b, ok := b.(ValueTuple)
+		return ok && d.listsEqual(a, b)
+	case ValueArray:
+		b, ok := b.(ValueArray)
+		return ok && d.listsEqual(a, b)
+	case ValueDictionary:
+		b, ok := b.(ValueDictionary)
+		return ok && d.mapsEqual(a, b)
+	case ValueMap:
+		b, ok := b.(ValueMap)
+		return ok && d.mapsEqual(a, b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func (d *differ) listsEqual(a, b []rbxfile.Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !d.valuesEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *differ) mapsEqual(a, b map[string]rbxfile.Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		otherValue, ok := b[key]
+		if !ok || !d.valuesEqual(value, otherValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *differ) diffProperties(a, b *Instance) []PropertyDiff {
+	a.PropertiesMutex.RLock()
+	defer a.PropertiesMutex.RUnlock()
+	b.PropertiesMutex.RLock()
+	defer b.PropertiesMutex.RUnlock()
+
+	var diffs []PropertyDiff
+	for name, oldValue := range a.Properties {
+		newValue := b.Properties[name]
+		if !d.valuesEqual(oldValue, newValue) {
+			diffs = append(diffs, PropertyDiff{Name: name, Old: oldValue, New: newValue})
+		}
+	}
+	for name, newValue := range b.Properties {
+		if _, ok := a.Properties[name]; !ok {
+			diffs = append(diffs, PropertyDiff{Name: name, New: newValue})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs
+}
+
+// Diff compares two DataModels. Instances are matched by their full name
+// and class name. Siblings that share a name and class are matched by
+// the number of properties they have in common. Instances that can't be
+// matched by their path are matched by name, class and properties
+// anywhere in the DataModel and reported as reparented.
+func Diff(a, b *DataModel) *DataModelDiff {
+	d := &differ{
+		matches:  make(map[*Instance]*Instance),
+		matchedB: make(map[*Instance]bool),
+	}
+	d.matchChildren(a.Instances, b.Instances)
+
+	isMatchedA := func(inst *Instance) bool {
+		_, ok := d.matches[inst]
+		return ok
+	}
+	isMatchedB := func(inst *Instance) bool {
+		return d.matchedB[inst]
+	}
+	// Try to find moved instances among the ones that weren't matched
+	unmatchedA := collectUnmatched(a.Instances, isMatchedA, nil)
+	unmatchedB := collectUnmatched(b.Instances, isMatchedB, nil)
+	for _, pair := range d.pairCandidates(unmatchedA, unmatchedB) {
+		d.matchChildren(pair[0].Children, pair[1].Children)
+	}
+
+	diff := &DataModelDiff{}
+	for _, inst := range collectUnmatched(b.Instances, isMatchedB, nil) {
+		if parent := diffParent(inst); parent == nil || d.matchedB[parent] {
+			diff.Added = append(diff.Added, inst)
+		}
+	}
+	for _, inst := range collectUnmatched(a.Instances, isMatchedA, nil) {
+		if parent := diffParent(inst); parent == nil || isMatchedA(parent) {
+			diff.Removed = append(diff.Removed, inst)
+		}
+	}
+	for _, pair := range d.pairs {
+		oldInst, newInst := pair[0], pair[1]
+		oldParent, newParent := diffParent(oldInst), diffParent(newInst)
+		var reparented bool
+		if oldParent == nil || newParent == nil {
+			reparented = oldParent != newParent
+		} else {
+			matchedParent, ok := d.matches[oldParent]
+			reparented = !ok || matchedParent != newParent
+		}
+		properties := d.diffProperties(oldInst, newInst)
+		if reparented || len(properties) != 0 {
+			diff.Changed = append(diff.Changed, &InstanceDiff{
+				Old:        oldInst,
+				New:        newInst,
+				Reparented: reparented,
+				Properties: properties,
+			})
+		}
+	}
+	return diff
+}
+
+func diffValueString(value rbxfile.Value) string {
+	if value == nil {
+		return "nil"
+	}
+	if ref, ok := value.(ValueReference); ok {
+		if ref.Instance == nil {
+			return "nil"
+		}
+		return ref.Instance.GetFullName()
+	}
+	return value.String()
+}
+
+// OldString returns the old value formatted for display
+func (property PropertyDiff) OldString() string {
+	return diffValueString(property.Old)
+}
+
+// NewString returns the new value formatted for display
+func (property PropertyDiff) NewString() string {
+	return diffValueString(property.New)
+}
+
+// String renders the diff as text. Added instances are prefixed with "+",
+// removed instances with "-" and changed instances with "~".
+func (diff *DataModelDiff) String() string {
+	var builder strings.Builder
+	for _, inst := range diff.Added {
+		fmt.Fprintf(&builder, "+ %s (%s)\n", inst.GetFullName(), inst.ClassName)
+	}
+	for _, inst := range diff.Removed {
+		fmt.Fprintf(&builder, "- %s (%s)\n", inst.GetFullName(), inst.ClassName)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(&builder, "~ %s (%s)\n", change.New.GetFullName(), change.New.ClassName)
+		if change.Reparented {
+			fmt.Fprintf(&builder, "\tmoved from %s\n", change.Old.GetFullName())
+		}
+		for _, property := range change.Properties {
+			fmt.Fprintf(&builder, "\t%s: %s -> %s\n", property.Name, property.OldString(), property.NewString())
+		}
+	}
+	return builder.String()
+}
+
+type jsonDiffInstance struct {
+	Path      string `json:"path"`
+	ClassName string `json:"className"`
+}
+
+type jsonDiffProperty struct {
+	Name    string `json:"name"`
+	OldType string `json:"oldType"`
+	Old     string `json:"old"`
+	NewType string `json:"newType"`
+	New     string `json:"new"`
+}
+
+type jsonDiffChange struct {
+	Path       string             `json:"path"`
+	OldPath    string             `json:"oldPath,omitempty"`
+	ClassName  string             `json:"className"`
+	Properties []jsonDiffProperty `json:"properties,omitempty"`
+}
+
+// MarshalJSON renders the diff as JSON. Values are written as strings.
+func (diff *DataModelDiff) MarshalJSON() ([]byte, error) {
+	output := struct {
+		Added   []jsonDiffInstance `json:"added"`
+		Removed []jsonDiffInstance `json:"removed"`
+		Changed []jsonDiffChange   `json:"changed"`
+	}{
+		Added:   make([]jsonDiffInstance, len(diff.Added)),
+		Removed: make([]jsonDiffInstance, len(diff.Removed)),
+		Changed: make([]jsonDiffChange, len(diff.Changed)),
+	}
+	for i, inst := range diff.Added {
+		output.Added[i] = jsonDiffInstance{inst.GetFullName(), inst.ClassName}
+	}
+	for i, inst := range diff.Removed {
+		output.Removed[i] = jsonDiffInstance{inst.GetFullName(), inst.ClassName}
+	}
+	for i, change := range diff.Changed {
+		jsonChange := jsonDiffChange{
+			Path:       change.New.GetFullName(),
+			ClassName:  change.New.ClassName,
+			Properties: make([]jsonDiffProperty, len(change.Properties)),
+		}
+		if change.Reparented {
+			jsonChange.OldPath = change.Old.GetFullName()
+		}
+		for j, property := range change.Properties {
+			jsonChange.Properties[j] = jsonDiffProperty{
+				Name:    property.Name,
+				OldType: TypeString(property.Old),
+				Old:     property.OldString(),
+				NewType: TypeString(property.New),
+				New:     property.NewString(),
+			}
+		}
+		output.Changed[i] = jsonChange
+	}
+	return json.Marshal(output)
+}
@@ -0,0 +1,87 @@
+package datamodel
+
+import (
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+// diffTestInstance creates a named instance
+func diffTestInstance(className string, name string, parent *Instance) *Instance {
+	instance, _ := NewInstance(className, parent)
+	instance.Set("Name", rbxfile.ValueString(name))
+	return instance
+}
+
+// diffTestModel returns a DataModel and its Workspace
+func diffTestModel() (*DataModel, *Instance) {
+	model := New()
+	workspace := diffTestInstance("Workspace", "Workspace", nil)
+	workspace.IsService = true
+	model.AddService(workspace)
+	return model, workspace
+}
+
+func TestDiffTokens(t *testing.T) {
+	oldModel, oldWorkspace := diffTestModel()
+	oldPart := diffTestInstance("Part", "Part", oldWorkspace)
+	// Tokens read from the network don't carry the enum names
+	oldPart.Set("Shape", ValueToken{ID: 3, Value: 1})
+	oldPart.Set("Material", ValueToken{ID: 4, Value: 256})
+	oldPart.Set("Arguments", ValueTuple{ValueToken{ID: 3, Value: 2}})
+
+	newModel, newWorkspace := diffTestModel()
+	newPart := diffTestInstance("Part", "Part", newWorkspace)
+	newPart.Set("Shape", ValueToken{ID: 0, Value: 1, EnumName: "PartType", ItemName: "Block"})
+	newPart.Set("Material", ValueToken{ID: 4, Value: 512})
+	newPart.Set("Arguments", ValueTuple{ValueToken{Value: 2, EnumName: "PartType"}})
+
+	diff := Diff(oldModel, newModel)
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 || len(diff.Changed) != 1 {
+		t.Fatalf("unexpected diff:\n%s", diff.String())
+	}
+	properties := diff.Changed[0].Properties
+	if len(properties) != 1 || properties[0].Name != "Material" {
+		t.Errorf("expected only Material to change, got %v", properties)
+	}
+}
+
+func TestDiffInstances(t *testing.T) {
+	oldModel, oldWorkspace := diffTestModel()
+	oldFolder := diffTestInstance("Folder", "Folder", oldWorkspace)
+	oldRed := diffTestInstance("Part", "Part", oldFolder)
+	oldRed.Set("Color", rbxfile.ValueColor3{R: 1})
+	oldBlue := diffTestInstance("Part", "Part", oldFolder)
+	oldBlue.Set("Color", rbxfile.ValueColor3{B: 1})
+	oldValue := diffTestInstance("ObjectValue", "Value", oldWorkspace)
+	oldValue.Set("Value", ValueReference{Instance: oldBlue})
+	removed := diffTestInstance("Model", "Removed", oldWorkspace)
+	diffTestInstance("Part", "RemovedChild", removed)
+
+	newModel, newWorkspace := diffTestModel()
+	newFolder := diffTestInstance("Folder", "Folder", newWorkspace)
+	// The blue part is moved out of the folder
+	newRed := diffTestInstance("Part", "Part", newFolder)
+	newRed.Set("Color", rbxfile.ValueColor3{R: 1})
+	newValue := diffTestInstance("ObjectValue", "Value", newWorkspace)
+	newBlue := diffTestInstance("Part", "Part", newWorkspace)
+	newBlue.Set("Color", rbxfile.ValueColor3{B: 1})
+	newValue.Set("Value", ValueReference{Instance: newBlue})
+	added := diffTestInstance("Model", "Added", newWorkspace)
+	diffTestInstance("Part", "AddedChild", added)
+
+	diff := Diff(oldModel, newModel)
+	if len(diff.Added) != 1 || diff.Added[0] != added {
+		t.Errorf("expected only the added model, got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != removed {
+		t.Errorf("expected only the removed model, got %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 {
+		t.Fatalf("unexpected changes:\n%s", diff.String())
+	}
+	change := diff.Changed[0]
+	if change.Old != oldBlue || change.New != newBlue || !change.Reparented || len(change.Properties) != 0 {
+		t.Errorf("moved part wasn't matched:\n%s", diff.String())
+	}
+}
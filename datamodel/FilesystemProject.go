@@ -0,0 +1,395 @@
+package datamodel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/robloxapi/rbxfile"
+	rbxjson "github.com/robloxapi/rbxfile/json"
+)
+
+// ProjectInstanceFile is the name of the file that describes the instance
+// in each directory of a project
+const ProjectInstanceFile = "instance.json"
+
+// ProjectManifestFile is the name of the file that lists the services
+// at the root of a project in order
+const ProjectManifestFile = "project.json"
+
+// projectScriptExtension is the extension of script bytecode files
+const projectScriptExtension = ".rbxc"
+
+// projectProperty is a property as written in a project instance file
+type projectProperty struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value,omitempty"`
+	// Signature and File are used for SignedProtectedStrings,
+	// whose bytecode is stored in a separate file
+	Signature string `json:"signature,omitempty"`
+	File      string `json:"file,omitempty"`
+}
+
+// projectInstance is the content of a project instance file
+type projectInstance struct {
+	ClassName  string                     `json:"className"`
+	IsService  bool                       `json:"isService,omitempty"`
+	Properties map[string]projectProperty `json:"properties"`
+	// Children lists the directory names of the children in order
+	Children []string `json:"children"`
+}
+
+// projectManifest is the content of the project manifest file
+type projectManifest struct {
+	// Services lists the directory names of the services in order
+	Services []string `json:"services"`
+}
+
+var signedProtectedStringName = CustomTypeNames[TypeSignedProtectedString]
+
+// projectFileName makes the instance name safe to use as a file name
+func projectFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// checkProjectName returns an error if the file name read from a project
+// could refer to a file outside of its directory
+func checkProjectName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	return nil
+}
+
+// projectWriter assigns paths to the instances of a rbxfile tree
+type projectWriter struct {
+	paths map[*rbxfile.Instance]string
+}
+
+func (writer *projectWriter) assignPaths(instances []*rbxfile.Instance, parentPath string, taken map[string]bool) []string {
+	names := make([]string, len(instances))
+	for i, inst := range instances {
+		base := projectFileName(inst.Name())
+		name := base
+		for n := 2; taken[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d)", base, n)
+		}
+		taken[strings.ToLower(name)] = true
+		names[i] = name
+		writer.paths[inst] = path.Join(parentPath, name)
+	}
+	for _, inst := range instances {
+		writer.assignPaths(inst.Children, writer.paths[inst], writer.reservedNames(inst))
+	}
+	return names
+}
+
+// reservedNames returns the file names that children of the instance can't use
+func (writer *projectWriter) reservedNames(inst *rbxfile.Instance) map[string]bool {
+	taken := map[string]bool{ProjectInstanceFile: true}
+	for name := range inst.Properties {
+		taken[strings.ToLower(projectFileName(name)+projectScriptExtension)] = true
+	}
+	return taken
+}
+
+func (writer *projectWriter) writeInstance(inst *rbxfile.Instance, location string) error {
+	dir := filepath.Join(location, filepath.FromSlash(writer.paths[inst]))
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	description := projectInstance{
+		ClassName:  inst.ClassName,
+		IsService:  inst.IsService,
+		Properties: make(map[string]projectProperty, len(inst.Properties)),
+		Children:   make([]string, len(inst.Children)),
+	}
+	for i, child := range inst.Children {
+		description.Children[i] = path.Base(writer.paths[child])
+	}
+	for name, value := range inst.Properties {
+		if signed, ok := value.(*ValueSignedProtectedString); ok {
+			value = *signed
+		}
+		switch value := value.(type) {
+		case rbxfile.ValueReference:
+			property := projectProperty{Type: value.Type().String()}
+			if target, ok := writer.paths[value.Instance]; ok {
+				property.Value = target
+			}
+			description.Properties[name] = property
+		case ValueSignedProtectedString:
+			if value.Value == nil {
+				continue
+			}
+			file := projectFileName(name) + projectScriptExtension
+			err = ioutil.WriteFile(filepath.Join(dir, file), value.Value.Value, 0644)
+			if err != nil {
+				return err
+			}
+			description.Properties[name] = projectProperty{
+				Type:      signedProtectedStringName,
+				Signature: base64.StdEncoding.EncodeToString(value.Signature),
+				File:      file,
+			}
+		default:
+			jsonValue := rbxjson.ValueToJSONInterface(value, nil)
+			if jsonValue == nil && value.Type() != rbxfile.TypeContent {
+				// This type can't be represented
+				continue
+			}
+			description.Properties[name] = projectProperty{
+				Type:  value.Type().String(),
+				Value: jsonValue,
+			}
+		}
+	}
+
+	contents, err := json.MarshalIndent(description, "", "\t")
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, ProjectInstanceFile), contents, 0644)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range inst.Children {
+		err = writer.writeInstance(child, location)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearProject removes the instance directories of a project at location.
+// Other files, such as .git, are kept.
+func clearProject(location string) error {
+	entries, err := ioutil.ReadDir(location)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(location, entry.Name())
+		_, err = os.Stat(filepath.Join(dir, ProjectInstanceFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		err = os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteProject writes the DataModel to location as a directory tree that
+// is easy to diff. Each instance is written as a directory containing an
+// instance.json file. Scripts are written as .rbxc bytecode files and
+// references are written as paths relative to location.
+// The instances of a project previously written to location are removed first.
+func (model *DataModel) WriteProject(location string) error {
+	err := clearProject(location)
+	if err != nil {
+		return err
+	}
+	root := model.ToRbxfile()
+	writer := &projectWriter{paths: make(map[*rbxfile.Instance]string)}
+	manifest := projectManifest{
+		Services: writer.assignPaths(root.Instances, "", map[string]bool{}),
+	}
+	for _, service := range root.Instances {
+		err = writer.writeInstance(service, location)
+		if err != nil {
+			return err
+		}
+	}
+	contents, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(location, ProjectManifestFile), contents, 0644)
+}
+
+// projectReference is a reference property that is resolved after all
+// instances have been read
+type projectReference struct {
+	instance *rbxfile.Instance
+	name     string
+	path     string
+}
+
+type projectReader struct {
+	location   string
+	instances  map[string]*rbxfile.Instance
+	references []projectReference
+}
+
+func (reader *projectReader) readInstance(instancePath string) (*rbxfile.Instance, error) {
+	dir := filepath.Join(reader.location, filepath.FromSlash(instancePath))
+	contents, err := ioutil.ReadFile(filepath.Join(dir, ProjectInstanceFile))
+	if err != nil {
+		return nil, err
+	}
+	var description projectInstance
+	err = json.Unmarshal(contents, &description)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", instancePath, err)
+	}
+
+	inst := rbxfile.NewInstance(description.ClassName, nil)
+	inst.IsService = description.IsService
+	reader.instances[instancePath] = inst
+	for name, property := range description.Properties {
+		switch property.Type {
+		case rbxfile.TypeReference.String():
+			target, _ := property.Value.(string)
+			reader.references = append(reader.references, projectReference{
+				instance: inst,
+				name:     name,
+				path:     target,
+			})
+		case signedProtectedStringName:
+			err = checkProjectName(property.File)
+			if err != nil {
+				return nil, fmt.Errorf("%s: property %s: %w", instancePath, name, err)
+			}
+			bytecode, err := ioutil.ReadFile(filepath.Join(dir, property.File))
+			if err != nil {
+				return nil, err
+			}
+			signature, err := base64.StdEncoding.DecodeString(property.Signature)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", instancePath, err)
+			}
+			// The hash depends on the peer that the script is sent to,
+			// so it is computed when the script is written
+			inst.Properties[name] = ValueSignedProtectedString{
+				Signature: signature,
+				Value:     &ValueDeferredString{Value: rbxfile.ValueSharedString(bytecode)},
+			}
+		default:
+			typ := rbxfile.TypeFromString(property.Type)
+			if typ == rbxfile.TypeInvalid {
+				return nil, fmt.Errorf("%s: unknown type %s for property %s", instancePath, property.Type, name)
+			}
+			value := rbxjson.ValueFromJSONInterface(typ, property.Value)
+			if value == nil {
+				return nil, fmt.Errorf("%s: invalid value for property %s", instancePath, name)
+			}
+			inst.Properties[name] = value
+		}
+	}
+
+	for _, childName := range description.Children {
+		err = checkProjectName(childName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", instancePath, err)
+		}
+		child, err := reader.readInstance(path.Join(instancePath, childName))
+		if err != nil {
+			return nil, err
+		}
+		inst.AddChild(child)
+	}
+	return inst, nil
+}
+
+// projectServices returns the directory names of the services of a project
+// in order. Projects without a manifest are read in directory order.
+func projectServices(location string) ([]string, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(location, ProjectManifestFile))
+	if err == nil {
+		var manifest projectManifest
+		err = json.Unmarshal(contents, &manifest)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ProjectManifestFile, err)
+		}
+		for _, name := range manifest.Services {
+			err = checkProjectName(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ProjectManifestFile, err)
+			}
+		}
+		return manifest.Services, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(location)
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// Skip directories that aren't part of the project, such as .git
+		_, err = os.Stat(filepath.Join(location, entry.Name(), ProjectInstanceFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		services = append(services, entry.Name())
+	}
+	return services, nil
+}
+
+// ReadProject reads a directory tree written by WriteProject.
+// The services are read in the order listed in the project manifest.
+// Child directories and script files must be within their parent directory.
+// The result can be converted to a DataModel using FromRbxfile().
+func ReadProject(location string) (*rbxfile.Root, error) {
+	services, err := projectServices(location)
+	if err != nil {
+		return nil, err
+	}
+	reader := &projectReader{
+		location:  location,
+		instances: make(map[string]*rbxfile.Instance),
+	}
+	root := &rbxfile.Root{}
+	for _, name := range services {
+		service, err := reader.readInstance(name)
+		if err != nil {
+			return nil, err
+		}
+		root.Instances = append(root.Instances, service)
+	}
+	if len(root.Instances) == 0 {
+		return nil, errors.New("no instances found in project")
+	}
+
+	for _, ref := range reader.references {
+		ref.instance.Properties[ref.name] = rbxfile.ValueReference{
+			Instance: reader.instances[ref.path],
+		}
+	}
+	return root, nil
+}
@@ -0,0 +1,128 @@
+package datamodel
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+// projectTestModel returns a DataModel with duplicate names, a reference and a script
+func projectTestModel() *DataModel {
+	model := New()
+	workspace, _ := NewInstance("Workspace", nil)
+	workspace.IsService = true
+	workspace.Set("Name", rbxfile.ValueString("Workspace"))
+	model.AddService(workspace)
+	// Services must keep their order although it isn't alphabetical
+	soundService, _ := NewInstance("SoundService", nil)
+	soundService.IsService = true
+	soundService.Set("Name", rbxfile.ValueString("SoundService"))
+	model.AddService(soundService)
+
+	first, _ := NewInstance("Part", workspace)
+	first.Set("Name", rbxfile.ValueString("Part"))
+	second, _ := NewInstance("Part", workspace)
+	second.Set("Name", rbxfile.ValueString("Part"))
+	second.Set("Transparency", rbxfile.ValueFloat(0.5))
+	value, _ := NewInstance("ObjectValue", workspace)
+	value.Set("Name", rbxfile.ValueString("Value"))
+	value.Set("Value", ValueReference{Instance: second})
+	script, _ := NewInstance("Script", first)
+	script.Set("Name", rbxfile.ValueString("Script"))
+	script.Set("Source", ValueSignedProtectedString{
+		Signature: []byte{1, 2, 3},
+		Value:     &ValueDeferredString{Value: rbxfile.ValueSharedString(testBytecode)},
+	})
+	return model
+}
+
+func TestProjectRoundTrip(t *testing.T) {
+	location, err := ioutil.TempDir("", "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(location)
+
+	// Instances of a previous project must not be read back
+	stale := filepath.Join(location, "Lighting")
+	os.Mkdir(stale, 0755)
+	ioutil.WriteFile(filepath.Join(stale, ProjectInstanceFile), []byte(`{"className":"Lighting"}`), 0644)
+	os.Mkdir(filepath.Join(location, ".git"), 0755)
+
+	err = projectTestModel().WriteProject(location)
+	if err != nil {
+		t.Fatalf("failed to write project: %s", err.Error())
+	}
+	if _, err = os.Stat(filepath.Join(location, ".git")); err != nil {
+		t.Errorf("unrelated directory was removed: %s", err.Error())
+	}
+	root, err := ReadProject(location)
+	if err != nil {
+		t.Fatalf("failed to read project: %s", err.Error())
+	}
+
+	if len(root.Instances) != 2 || root.Instances[0].ClassName != "Workspace" || root.Instances[1].ClassName != "SoundService" {
+		t.Fatalf("unexpected services in project: %v", root.Instances)
+	}
+	children := root.Instances[0].Children
+	if len(children) != 3 {
+		t.Fatalf("expected 3 children, got %d", len(children))
+	}
+	first, second, value := children[0], children[1], children[2]
+	if first.Name() != "Part" || second.Name() != "Part" || len(first.Children) != 1 {
+		t.Errorf("instances with duplicate names weren't kept: %v", children)
+	}
+	if second.Get("Transparency") != rbxfile.ValueFloat(0.5) {
+		t.Errorf("instances with duplicate names were swapped: %v", second.Get("Transparency"))
+	}
+	if ref, ok := value.Get("Value").(rbxfile.ValueReference); !ok || ref.Instance != second {
+		t.Errorf("reference doesn't point to the second part: %#v", value.Get("Value"))
+	}
+	script, ok := first.Children[0].Get("Source").(ValueSignedProtectedString)
+	if !ok || script.Value == nil {
+		t.Fatalf("script wasn't read: %#v", first.Children[0].Get("Source"))
+	}
+	if !bytes.Equal(script.Signature, []byte{1, 2, 3}) || !bytes.Equal(script.Value.Value, testBytecode) {
+		t.Errorf("script wasn't kept: %v", script)
+	}
+	if script.Value.Hash != "" {
+		t.Errorf("script hash was computed without knowing the peer: %X", script.Value.Hash)
+	}
+}
+
+func TestReadProjectTraversal(t *testing.T) {
+	for _, description := range []projectInstance{
+		{ClassName: "Workspace", Children: []string{"../outside"}},
+		{ClassName: "Workspace", Children: []string{".."}},
+		{ClassName: "Workspace", Properties: map[string]projectProperty{
+			"Source": {Type: signedProtectedStringName, File: "../secret"},
+		}},
+	} {
+		location, err := ioutil.TempDir("", "project")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(location)
+		// The targets exist, so only the name check can reject the project
+		outside := filepath.Join(location, "outside")
+		os.Mkdir(outside, 0755)
+		ioutil.WriteFile(filepath.Join(outside, ProjectInstanceFile), []byte(`{"className":"Folder"}`), 0644)
+		ioutil.WriteFile(filepath.Join(location, ProjectInstanceFile), []byte(`{"className":"Folder"}`), 0644)
+		ioutil.WriteFile(filepath.Join(location, "secret"), testBytecode, 0644)
+		dir := filepath.Join(location, "Workspace")
+		os.Mkdir(dir, 0755)
+		contents, _ := json.Marshal(description)
+		ioutil.WriteFile(filepath.Join(dir, ProjectInstanceFile), contents, 0644)
+
+		_, err = ReadProject(location)
+		if err == nil || !strings.Contains(err.Error(), "invalid file name") {
+			t.Errorf("project with %s wasn't rejected: %v", contents, err)
+		}
+	}
+}
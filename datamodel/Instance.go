@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/olebedev/emitter"
 	"github.com/robloxapi/rbxfile"
@@ -22,8 +23,21 @@ type Instance struct {
 	EventEmitter    *emitter.Emitter
 	ParentEmitter   *emitter.Emitter
 	parent          *Instance
+	// destroyed is protected by PropertiesMutex
+	destroyed bool
+	// emitting counts the emissions in progress on the instance's emitters.
+	// The emitters call listeners while holding their lock, so Destroy()
+	// must not use them synchronously while a listener is running.
+	emitting int32
 }
 
+// ErrInstanceDestroyed is returned by functions that wait for changes
+// to an instance that has been destroyed
+var ErrInstanceDestroyed = errors.New("instance has been destroyed")
+
+// ErrParentLocked is returned when the parent of a destroyed instance is changed
+var ErrParentLocked = errors.New("parent of a destroyed instance is locked")
+
 func NewInstance(className string, parent *Instance) (*Instance, error) {
 	inst := &Instance{
 		ClassName:       className,
@@ -43,40 +57,69 @@ func (instance *Instance) HasAncestor(ancestor *Instance) bool {
 		return true
 	}
 	for instance != nil {
-		if instance.parent == ancestor {
+		parent := instance.Parent()
+		if parent == ancestor {
 			return true
 		}
-		instance = instance.parent
+		instance = parent
 	}
 	return false
 }
 
+// emit emits an event on one of the instance's emitters and waits for it
+func (instance *Instance) emit(instanceEmitter *emitter.Emitter, topic string, args ...interface{}) {
+	atomic.AddInt32(&instance.emitting, 1)
+	<-instanceEmitter.Emit(topic, args...)
+	atomic.AddInt32(&instance.emitting, -1)
+}
+
+// detach removes the instance from its parent's children without emitting.
+// The parent is protected by the instance's PropertiesMutex and the children
+// by the parent's PropertiesMutex.
+func (instance *Instance) detach() {
+	instance.PropertiesMutex.Lock()
+	oldParent := instance.parent
+	instance.parent = nil
+	instance.PropertiesMutex.Unlock()
+	if oldParent == nil {
+		return
+	}
+	oldParent.PropertiesMutex.Lock()
+	defer oldParent.PropertiesMutex.Unlock()
+	for i, c := range oldParent.Children {
+		if c == instance {
+			copy(oldParent.Children[i:], oldParent.Children[i+1:])
+			oldParent.Children[len(oldParent.Children)-1] = nil
+			oldParent.Children = oldParent.Children[:len(oldParent.Children)-1]
+			break
+		}
+	}
+}
+
 func (instance *Instance) AddChild(child *Instance) error {
 	if instance.HasAncestor(child) {
 		return errors.New("instance references can't be cyclic")
 	}
-	oldParent := child.parent
-	if oldParent != nil {
-		for i, c := range oldParent.Children {
-			if c == child {
-				copy(oldParent.Children[i:], oldParent.Children[i+1:])
-				oldParent.Children[len(oldParent.Children)-1] = nil
-				oldParent.Children = oldParent.Children[:len(oldParent.Children)-1]
-			}
-		}
+	if child.IsDestroyed() {
+		return ErrParentLocked
 	}
+	child.detach()
 
+	child.PropertiesMutex.Lock()
 	child.parent = instance
+	child.PropertiesMutex.Unlock()
 	if instance != nil {
+		instance.PropertiesMutex.Lock()
 		instance.Children = append(instance.Children, child)
-		<-instance.ChildEmitter.Emit(child.Name(), child)
+		instance.PropertiesMutex.Unlock()
+		instance.emit(instance.ChildEmitter, child.Name(), child)
 	}
 
 	parentName := ""
 	if instance != nil {
 		parentName = instance.Name()
 	}
-	<-child.ParentEmitter.Emit(parentName, instance)
+	child.emit(child.ParentEmitter, parentName, instance)
 	return nil
 }
 
@@ -89,15 +132,125 @@ func (instance *Instance) Set(name string, value rbxfile.Value) {
 	instance.PropertiesMutex.Lock()
 	instance.Properties[name] = value
 	instance.PropertiesMutex.Unlock()
-	<-instance.PropertyEmitter.Emit(name, value)
+	instance.emit(instance.PropertyEmitter, name, value)
 }
 
 func (instance *Instance) SetParent(parent *Instance) error {
 	return parent.AddChild(instance)
 }
 
+// IsDestroyed returns whether Destroy() has been called on the instance
+// or one of its ancestors
+func (instance *Instance) IsDestroyed() bool {
+	instance.PropertiesMutex.RLock()
+	defer instance.PropertiesMutex.RUnlock()
+	return instance.destroyed
+}
+
+// markDestroyed locks the parent of the instance. It returns false
+// if the instance had already been destroyed.
+func (instance *Instance) markDestroyed() bool {
+	instance.PropertiesMutex.Lock()
+	defer instance.PropertiesMutex.Unlock()
+	if instance.destroyed {
+		return false
+	}
+	instance.destroyed = true
+	return true
+}
+
+// closeEmitters removes all listeners of the instance's emitters.
+// Functions waiting for changes to the instance will return ErrInstanceDestroyed.
+func (instance *Instance) closeEmitters() {
+	for _, instanceEmitter := range []*emitter.Emitter{
+		instance.ChildEmitter,
+		instance.PropertyEmitter,
+		instance.EventEmitter,
+		instance.ParentEmitter,
+	} {
+		// Off("*") wouldn't match topics that contain a slash
+		for _, topic := range instanceEmitter.Topics() {
+			instanceEmitter.Off(topic)
+		}
+	}
+}
+
+// appendDescendants appends the descendants of the instance to list in pre-order
+func (instance *Instance) appendDescendants(list []*Instance) []*Instance {
+	instance.PropertiesMutex.RLock()
+	children := append([]*Instance(nil), instance.Children...)
+	instance.PropertiesMutex.RUnlock()
+	for _, child := range children {
+		list = append(list, child)
+		list = child.appendDescendants(list)
+	}
+	return list
+}
+
+// Destroy works like Instance:Destroy() in Roblox.
+// The Destroying event is fired for the instance and its descendants,
+// after which they are parented to nil and their parents are locked.
+// All listeners of the emitters are removed. Only the parent change
+// of the instance itself is emitted.
+// Destroying an instance twice has no effect.
+//
+// Destroy may be called from a listener of the instance's emitters.
+// In that case, the instances are only marked as destroyed immediately.
+// The rest of the sequence runs asynchronously once Destroy has returned.
+func (instance *Instance) Destroy() {
+	if !instance.markDestroyed() {
+		return
+	}
+	instances := instance.appendDescendants([]*Instance{instance})
+	descendants := instances[1:]
+	for _, descendant := range descendants {
+		descendant.markDestroyed()
+	}
+
+	fireDestroying := func() {
+		for _, inst := range instances {
+			inst.emit(inst.EventEmitter, "Destroying", []rbxfile.Value(nil))
+		}
+	}
+	// Only one parent change is emitted, so the descendants
+	// are detached silently, deepest first
+	detach := func() {
+		for i := len(descendants) - 1; i >= 0; i-- {
+			descendants[i].detach()
+		}
+		instance.detach()
+	}
+	shutdown := func() {
+		instance.emit(instance.ParentEmitter, "", (*Instance)(nil))
+		for _, inst := range instances {
+			inst.closeEmitters()
+		}
+	}
+
+	reentrant := false
+	for _, inst := range instances {
+		if atomic.LoadInt32(&inst.emitting) != 0 {
+			reentrant = true
+			break
+		}
+	}
+	destroy := func() {
+		fireDestroying()
+		detach()
+		shutdown()
+	}
+	if reentrant {
+		go destroy()
+		return
+	}
+	destroy()
+}
+
 func (instance *Instance) FindFirstChild(name string) *Instance {
-	for _, child := range instance.Children {
+	instance.PropertiesMutex.RLock()
+	children := append([]*Instance(nil), instance.Children...)
+	instance.PropertiesMutex.RUnlock()
+	for _, child := range children {
 		if child.Name() == name {
 			return child
 		}
@@ -109,13 +262,21 @@ func (instance *Instance) waitForChild(ctx context.Context, name string) (*Insta
 		return child, nil
 	}
 	emitterChan := instance.ChildEmitter.Once(name)
+	// The emitters are closed after the instance is marked as destroyed
+	if instance.IsDestroyed() {
+		instance.ChildEmitter.Off(name, emitterChan)
+		return nil, ErrInstanceDestroyed
+	}
 	// If the child is added while we created the emitter
 	if child := instance.FindFirstChild(name); child != nil {
 		instance.ChildEmitter.Off(name, emitterChan)
 		return child, nil
 	}
 	select {
-	case e := <-emitterChan:
+	case e, ok := <-emitterChan:
+		if !ok {
+			return nil, ErrInstanceDestroyed
+		}
 		// No need to unbind because it's Once()
 		child := e.Args[0].(*Instance)
 		return child, nil
@@ -139,10 +300,18 @@ func (instance *Instance) WaitForChild(ctx context.Context, names ...string) (*I
 func (instance *Instance) WaitForProp(ctx context.Context, name string) (rbxfile.Value, error) {
 	instance.PropertiesMutex.RLock()
 	emitterChan := instance.PropertyEmitter.Once(name)
+	destroyed := instance.destroyed
 	instance.PropertiesMutex.RUnlock()
+	if destroyed {
+		instance.PropertyEmitter.Off(name, emitterChan)
+		return nil, ErrInstanceDestroyed
+	}
 
 	select {
-	case e := <-emitterChan:
+	case e, ok := <-emitterChan:
+		if !ok {
+			return nil, ErrInstanceDestroyed
+		}
 		return e.Args[0].(rbxfile.Value), nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -156,10 +325,18 @@ func (instance *Instance) WaitForRefProp(ctx context.Context, name string) (*Ins
 		return currProp.(ValueReference).Instance, nil
 	}
 	propEvtChan := instance.PropertyEmitter.On(name)
+	destroyed := instance.destroyed
 	instance.PropertiesMutex.RUnlock()
+	if destroyed {
+		instance.PropertyEmitter.Off(name, propEvtChan)
+		return nil, ErrInstanceDestroyed
+	}
 	for {
 		select {
-		case propEvt := <-propEvtChan:
+		case propEvt, ok := <-propEvtChan:
+			if !ok {
+				return nil, ErrInstanceDestroyed
+			}
 			if propEvt.Args[0] == nil {
 				continue
 			}
@@ -193,7 +370,7 @@ func (instance *Instance) GetFullName() string {
 	parts := make([]string, 0, 8)
 	for instance != nil {
 		parts = append([]string{instance.Name()}, parts...)
-		instance = instance.parent
+		instance = instance.Parent()
 	}
 	var builder strings.Builder
 	for _, part := range parts {
@@ -203,15 +380,17 @@ func (instance *Instance) GetFullName() string {
 	return builder.String()[1:]
 }
 func (instance *Instance) FireEvent(name string, args ...rbxfile.Value) {
-	<-instance.EventEmitter.Emit(name, []rbxfile.Value(args))
+	instance.emit(instance.EventEmitter, name, []rbxfile.Value(args))
 }
 
 func (instance *Instance) Parent() *Instance {
+	instance.PropertiesMutex.RLock()
+	defer instance.PropertiesMutex.RUnlock()
 	return instance.parent
 }
 
 func (instance *Instance) Copy(pool *SelfReferencePool) *Instance {
-	newInst := pool.MakeWithRef(instance.Ref.String())
+	newInst := pool.MakeCopy(instance)
 	newInst.ClassName = instance.ClassName
 	newInst.Ref = instance.Ref
 	newInst.Children = make([]*Instance, len(instance.Children))
@@ -227,7 +406,7 @@ func (instance *Instance) Copy(pool *SelfReferencePool) *Instance {
 		// hence we need to set it again here
 		if value.Type() == TypeReference {
 			newInst.Properties[name] = ValueReference{
-				Instance:  pool.MakeWithRef(value.(ValueReference).Reference.String()),
+				Instance:  pool.MakeCopy(value.(ValueReference).Instance),
 				Reference: value.(ValueReference).Reference,
 			}
 		}
@@ -5,7 +5,9 @@ import (
 	"math/rand"
 )
 
-// TODO: should work with PeerID
+// InstanceDictionary generates references for new instances.
+// The references are identified by PeerID and InstanceIndex; Scope is only
+// used for display and for looking up references that don't carry a peer ID.
 type InstanceDictionary struct {
 	Scope         string
 	PeerID        uint32
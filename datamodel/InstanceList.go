@@ -4,13 +4,21 @@ import (
 	"errors"
 )
 
-// TODO: Should work with PeerID
 type instanceScope struct {
 	Instances map[uint32]*Instance
 }
 
+// InstanceList indexes instances by their references.
+// References that carry a peer ID are resolved by (PeerId, Id), so that
+// the same instance is found regardless of the scope name that the reference
+// was created with. References without a peer ID are resolved by (Scope, Id).
+// Once a scope is known to belong to a peer, such references are resolved
+// using the peer instead.
 type InstanceList struct {
+	peers  map[uint32]*instanceScope
 	scopes map[string]*instanceScope
+	// peerScopes maps scope names to the peer that uses them
+	peerScopes map[string]uint32
 }
 
 var ErrNullInstance = errors.New("instance is null")
@@ -23,14 +31,60 @@ func newInstanceScope() *instanceScope {
 }
 
 func NewInstanceList() *InstanceList {
-	return &InstanceList{scopes: make(map[string]*instanceScope)}
+	return &InstanceList{
+		peers:      make(map[uint32]*instanceScope),
+		scopes:     make(map[string]*instanceScope),
+		peerScopes: make(map[string]uint32),
+	}
 }
 
 func (s *instanceScope) remove(id uint32) {
 	delete(s.Instances, id)
 }
 
+func (l *InstanceList) getPeer(peerID uint32) *instanceScope {
+	peer, ok := l.peers[peerID]
+	if !ok {
+		peer = newInstanceScope()
+		l.peers[peerID] = peer
+	}
+	return peer
+}
+
+// bindScope associates the scope name with the peer. Instances that were
+// added using only the scope name are migrated to the peer. Their
+// references aren't modified, as copies of them may be held elsewhere.
+func (l *InstanceList) bindScope(scopeName string, peerID uint32) {
+	if scopeName == "" || l.peerScopes[scopeName] == peerID {
+		return
+	}
+	l.peerScopes[scopeName] = peerID
+
+	scope, ok := l.scopes[scopeName]
+	if !ok {
+		return
+	}
+	delete(l.scopes, scopeName)
+	peer := l.getPeer(peerID)
+	for id, instance := range scope.Instances {
+		if _, exists := peer.Instances[id]; exists {
+			continue
+		}
+		peer.Instances[id] = instance
+	}
+}
+
+// getScope returns the scope that instances with the reference are added to,
+// creating it if needed
 func (l *InstanceList) getScope(ref Reference) *instanceScope {
+	if ref.PeerId != 0 {
+		l.bindScope(ref.Scope, ref.PeerId)
+		return l.getPeer(ref.PeerId)
+	}
+	if peerID, ok := l.peerScopes[ref.Scope]; ok {
+		return l.getPeer(peerID)
+	}
+
 	scope, ok := l.scopes[ref.Scope]
 	if !ok {
 		scope = newInstanceScope()
@@ -39,11 +93,45 @@ func (l *InstanceList) getScope(ref Reference) *instanceScope {
 	return scope
 }
 
+// lookup returns the instance with the reference, or nil if it
+// doesn't exist. Unlike getScope, it doesn't modify the list.
+func (l *InstanceList) lookup(ref Reference) *Instance {
+	if ref.PeerId != 0 {
+		if peer, ok := l.peers[ref.PeerId]; ok {
+			if instance, ok := peer.Instances[ref.Id]; ok {
+				return instance
+			}
+		}
+		// The instance may have been added using only the scope name
+		// before the scope was bound to the peer
+		if _, ok := l.peerScopes[ref.Scope]; ok {
+			return nil
+		}
+	} else if peerID, ok := l.peerScopes[ref.Scope]; ok {
+		if peer, ok := l.peers[peerID]; ok {
+			return peer.Instances[ref.Id]
+		}
+		return nil
+	}
+	if scope, ok := l.scopes[ref.Scope]; ok {
+		return scope.Instances[ref.Id]
+	}
+	return nil
+}
+
+// BindPeerScope sets the scope name used by the peer, for example
+// when the server's peer ID becomes known. Instances that were added
+// using only the scope name are migrated to the peer, and references
+// to the peer with either scope name resolve to the same instances.
+func (l *InstanceList) BindPeerScope(peerID uint32, scopeName string) {
+	l.bindScope(scopeName, peerID)
+}
+
 func (l *InstanceList) CreateInstance(ref Reference) (*Instance, error) {
 	if ref.IsNull {
 		return nil, ErrNullInstance
 	}
-	instance := l.getScope(ref).Instances[ref.Id]
+	instance := l.lookup(ref)
 	if instance == nil {
 		instance, _ = NewInstance("", nil)
 		instance.Ref = ref
@@ -59,7 +147,7 @@ func (l *InstanceList) TryGetInstance(ref Reference) (*Instance, error) {
 		return nil, nil
 	}
 
-	instance := l.getScope(ref).Instances[ref.Id]
+	instance := l.lookup(ref)
 	if instance == nil {
 		return nil, ErrInstanceDoesntExist
 	}
@@ -78,7 +166,10 @@ func (l *InstanceList) Populate(instances []*Instance) {
 }
 
 func (l *InstanceList) RemoveTree(instance *Instance) {
-	l.getScope(instance.Ref).remove(instance.Ref.Id)
+	// Only remove the instance if the reference hasn't been reused
+	if l.lookup(instance.Ref) == instance {
+		l.getScope(instance.Ref).remove(instance.Ref.Id)
+	}
 
 	for _, child := range instance.Children {
 		l.RemoveTree(child)
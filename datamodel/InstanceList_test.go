@@ -0,0 +1,105 @@
+package datamodel
+
+import (
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+func TestInstanceListBindPeerScope(t *testing.T) {
+	list := NewInstanceList()
+
+	// The server's peer ID isn't known yet, so its scope name is generic
+	earlyRef := Reference{Scope: "RBXPID5", Id: 1, PeerId: 5}
+	early, err := list.CreateInstance(earlyRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	earlyValue := ValueReference{Instance: early, Reference: earlyRef}
+	// A reference that doesn't carry a peer ID, for example from a file
+	scopeRef := Reference{Scope: "RBXServer", Id: 2}
+	scoped, _ := NewInstance("Part", nil)
+	scoped.Ref = scopeRef
+	list.AddInstance(scopeRef, scoped)
+
+	// Lookups must not modify the list
+	if _, err := list.TryGetInstance(Reference{Scope: "RBXPID7", Id: 1, PeerId: 7}); err != ErrInstanceDoesntExist {
+		t.Errorf("expected ErrInstanceDoesntExist, got %v", err)
+	}
+	if _, err := list.TryGetInstance(Reference{Scope: "Missing", Id: 1}); err != ErrInstanceDoesntExist {
+		t.Errorf("expected ErrInstanceDoesntExist, got %v", err)
+	}
+	if len(list.peers) != 1 || len(list.scopes) != 1 || len(list.peerScopes) != 1 {
+		t.Errorf("lookups modified the list: %d peers, %d scopes, %d bound scopes", len(list.peers), len(list.scopes), len(list.peerScopes))
+	}
+
+	list.BindPeerScope(5, "RBXServer")
+
+	serverRef := Reference{Scope: "RBXServer", Id: 1, PeerId: 5}
+	if instance, _ := list.TryGetInstance(serverRef); instance != early {
+		t.Error("instance created before the peer's scope was bound wasn't found")
+	}
+	if instance, _ := list.TryGetInstance(earlyRef); instance != early {
+		t.Error("instance wasn't found with its original reference")
+	}
+	if early.Ref != earlyRef {
+		t.Errorf("binding modified the reference of the instance: %s", early.Ref.String())
+	}
+	if !earlyValue.Reference.Equal(&serverRef) {
+		t.Error("references with different scope names for the same peer aren't equal")
+	}
+
+	migratedRef := Reference{Scope: "RBXServer", Id: 2, PeerId: 5}
+	if instance, _ := list.TryGetInstance(migratedRef); instance != scoped {
+		t.Error("instance added using only the scope name wasn't migrated to the peer")
+	}
+	if scoped.Ref != scopeRef {
+		t.Errorf("migration modified the reference of the instance: %s", scoped.Ref.String())
+	}
+	if !scopeRef.Equal(&migratedRef) || !migratedRef.Equal(&scopeRef) {
+		t.Error("reference without a peer ID isn't equal to the migrated reference")
+	}
+	if other := (Reference{Scope: "RBXPID6", Id: 2}); scopeRef.Equal(&other) {
+		t.Error("references with different scopes are equal")
+	}
+
+	list.RemoveTree(scoped)
+	if _, err := list.TryGetInstance(migratedRef); err != ErrInstanceDoesntExist {
+		t.Errorf("migrated instance wasn't removed: %v", err)
+	}
+}
+
+func TestInstanceCopyAfterBind(t *testing.T) {
+	list := NewInstanceList()
+	earlyRef := Reference{Scope: "RBXPID5", Id: 1, PeerId: 5}
+	model, _ := list.CreateInstance(earlyRef)
+	model.ClassName = "Model"
+	list.BindPeerScope(5, "RBXServer")
+
+	// The reference was read after the scope was bound, so its scope
+	// differs from the one of the instance
+	partRef := Reference{Scope: "RBXServer", Id: 2, PeerId: 5}
+	part, _ := list.CreateInstance(partRef)
+	part.ClassName = "Part"
+	model.AddChild(part)
+	model.Set("PrimaryPart", ValueReference{Instance: part, Reference: partRef})
+	part.Set("Model", ValueReference{Instance: model, Reference: Reference{Scope: "RBXServer", Id: 1, PeerId: 5}})
+
+	modelCopy := model.Copy(NewSelfReferencePool())
+	partCopy := modelCopy.Children[0]
+	if modelCopy.Get("PrimaryPart").(ValueReference).Instance != partCopy {
+		t.Error("copied reference doesn't point to the copy of the child")
+	}
+	if partCopy.Get("Model").(ValueReference).Instance != modelCopy {
+		t.Error("copied reference doesn't point to the copy of the parent")
+	}
+
+	modelFile := model.ToRbxfile(NewRbxfileReferencePool())
+	partFile := modelFile.Children[0]
+	if modelFile.Get("PrimaryPart").(rbxfile.ValueReference).Instance != partFile {
+		t.Error("exported reference doesn't point to the exported child")
+	}
+	if partFile.Get("Model").(rbxfile.ValueReference).Instance != modelFile {
+		t.Error("exported reference doesn't point to the exported parent")
+	}
+}
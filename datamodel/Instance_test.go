@@ -0,0 +1,146 @@
+package datamodel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olebedev/emitter"
+)
+
+// waitDestroyed waits until the listener channel has been closed
+func waitDestroyed(t *testing.T, channel <-chan emitter.Event) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-channel:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("emitter wasn't closed")
+		}
+	}
+}
+
+func TestInstanceDestroy(t *testing.T) {
+	workspace, _ := NewInstance("Workspace", nil)
+	model, _ := NewInstance("Model", workspace)
+	part, _ := NewInstance("Part", model)
+
+	var destroying []string
+	for _, inst := range []*Instance{model, part} {
+		inst := inst
+		inst.EventEmitter.On("Destroying", func(e *emitter.Event) {
+			destroying = append(destroying, inst.ClassName)
+		}, emitter.Void)
+	}
+	parentChanges := 0
+	model.ParentEmitter.On("*", func(e *emitter.Event) {
+		parentChanges++
+	}, emitter.Void)
+	part.ParentEmitter.On("*", func(e *emitter.Event) {
+		t.Error("parent change of a descendant was emitted")
+	}, emitter.Void)
+	propertyChannel := part.PropertyEmitter.On("Size")
+
+	waiters := make(chan error, 3)
+	go func() {
+		_, err := model.WaitForChild(context.Background(), "Missing")
+		waiters <- err
+	}()
+	go func() {
+		_, err := part.WaitForProp(context.Background(), "Size")
+		waiters <- err
+	}()
+	go func() {
+		_, err := part.WaitForRefProp(context.Background(), "Target")
+		waiters <- err
+	}()
+	// Let the waiters start waiting
+	time.Sleep(10 * time.Millisecond)
+
+	model.Destroy()
+
+	if len(destroying) != 2 || destroying[0] != "Model" || destroying[1] != "Part" {
+		t.Errorf("Destroying wasn't fired for the instance and its descendants: %v", destroying)
+	}
+	if parentChanges != 1 {
+		t.Errorf("expected 1 parent change, got %d", parentChanges)
+	}
+	if model.Parent() != nil || part.Parent() != nil || len(workspace.Children) != 0 || len(model.Children) != 0 {
+		t.Error("instances weren't parented to nil")
+	}
+	if !model.IsDestroyed() || !part.IsDestroyed() {
+		t.Error("instances weren't marked as destroyed")
+	}
+	if err := model.SetParent(workspace); err != ErrParentLocked {
+		t.Errorf("expected ErrParentLocked, got %v", err)
+	}
+	if err := workspace.AddChild(part); err != ErrParentLocked {
+		t.Errorf("expected ErrParentLocked, got %v", err)
+	}
+	waitDestroyed(t, propertyChannel)
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-waiters:
+			if err != ErrInstanceDestroyed {
+				t.Errorf("expected ErrInstanceDestroyed, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("waiter wasn't released")
+		}
+	}
+	if _, err := part.WaitForProp(context.Background(), "Size"); err != ErrInstanceDestroyed {
+		t.Errorf("expected ErrInstanceDestroyed after destruction, got %v", err)
+	}
+
+	// Destroying twice has no effect
+	model.Destroy()
+}
+
+func TestInstanceDestroyFromListener(t *testing.T) {
+	workspace, _ := NewInstance("Workspace", nil)
+	folder, _ := NewInstance("Folder", workspace)
+
+	// Destroyed from its own Destroying listener
+	first, _ := NewInstance("Part", workspace)
+	first.EventEmitter.On("Destroying", func(e *emitter.Event) {
+		first.Destroy()
+	}, emitter.Void)
+	firstChannel := first.EventEmitter.On("Touched")
+
+	// Destroyed when it is moved to another parent
+	second, _ := NewInstance("Part", workspace)
+	second.ParentEmitter.On("*", func(e *emitter.Event) {
+		second.Destroy()
+	}, emitter.Void)
+	secondChannel := second.ParentEmitter.On("Nothing")
+	// Listeners must see the instance before it is parented to nil
+	var parentWhenDestroying *Instance
+	second.EventEmitter.On("Destroying", func(e *emitter.Event) {
+		parentWhenDestroying = second.Parent()
+	}, emitter.Void)
+
+	done := make(chan struct{})
+	go func() {
+		first.Destroy()
+		second.SetParent(folder)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Destroy() deadlocked")
+	}
+
+	waitDestroyed(t, firstChannel)
+	waitDestroyed(t, secondChannel)
+	if second.Parent() != nil || len(folder.Children) != 0 {
+		t.Error("instance destroyed from a listener wasn't parented to nil")
+	}
+	if parentWhenDestroying != folder {
+		t.Errorf("Destroying was fired after detaching: %v", parentWhenDestroying)
+	}
+}
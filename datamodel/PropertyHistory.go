@@ -0,0 +1,146 @@
+package datamodel
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+// DefaultPropertyHistoryLimit is the default number of values kept for each property
+const DefaultPropertyHistoryLimit = 256
+
+// PropertyRecord describes a single value that a property took
+type PropertyRecord struct {
+	// UniqueID is the unique ID of the packet that set the value
+	UniqueID  uint64
+	Timestamp time.Time
+	// Version is the property version sent with the value, or -1 if there was none
+	Version    int32
+	Value      rbxfile.Value
+	FromClient bool
+}
+
+// Direction returns "client" or "server" depending on who sent the value
+func (record PropertyRecord) Direction() string {
+	if record.FromClient {
+		return "client"
+	}
+	return "server"
+}
+
+// PropertyHistory keeps the latest values of each instance's properties.
+// The history of removed instances must be dropped using Forget().
+type PropertyHistory struct {
+	mutex sync.RWMutex
+	// Limit is the maximum number of values kept for each property.
+	// If it is zero or negative, all values are kept.
+	Limit   int
+	records map[*Instance]map[string][]PropertyRecord
+}
+
+// NewPropertyHistory returns an empty PropertyHistory that keeps at most
+// limit values for each property
+func NewPropertyHistory(limit int) *PropertyHistory {
+	return &PropertyHistory{
+		Limit:   limit,
+		records: make(map[*Instance]map[string][]PropertyRecord),
+	}
+}
+
+// Record adds a value to the property's history.
+// It does nothing if history is nil.
+func (history *PropertyHistory) Record(instance *Instance, name string, record PropertyRecord) {
+	if history == nil {
+		return
+	}
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	properties, ok := history.records[instance]
+	if !ok {
+		properties = make(map[string][]PropertyRecord)
+		history.records[instance] = properties
+	}
+	records := append(properties[name], record)
+	if history.Limit > 0 && len(records) > history.Limit {
+		records = append([]PropertyRecord(nil), records[len(records)-history.Limit:]...)
+	}
+	properties[name] = records
+}
+
+// Forget drops the history of the instance and its descendants.
+// It should be called when the instances are removed from the DataModel.
+// It does nothing if history is nil.
+func (history *PropertyHistory) Forget(instance *Instance) {
+	if history == nil {
+		return
+	}
+	instances := instance.appendDescendants([]*Instance{instance})
+	history.mutex.Lock()
+	for _, inst := range instances {
+		delete(history.records, inst)
+	}
+	history.mutex.Unlock()
+}
+
+// Get returns the recorded values of the property, oldest first
+func (history *PropertyHistory) Get(instance *Instance, name string) []PropertyRecord {
+	if history == nil {
+		return nil
+	}
+	history.mutex.RLock()
+	defer history.mutex.RUnlock()
+	return append([]PropertyRecord(nil), history.records[instance][name]...)
+}
+
+// Properties returns the sorted names of the instance's properties
+// that have a history
+func (history *PropertyHistory) Properties(instance *Instance) []string {
+	if history == nil {
+		return nil
+	}
+	history.mutex.RLock()
+	names := make([]string, 0, len(history.records[instance]))
+	for name := range history.records[instance] {
+		names = append(names, name)
+	}
+	history.mutex.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// WriteCSV writes the history of all of the instance's properties to w
+func (history *PropertyHistory) WriteCSV(w io.Writer, instance *Instance) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"Property", "UniqueID", "Timestamp", "Version", "Direction", "Type", "Value"})
+	if err != nil {
+		return err
+	}
+	for _, name := range history.Properties(instance) {
+		for _, record := range history.Get(instance, name) {
+			var valueType, value string
+			if record.Value != nil {
+				valueType = TypeString(record.Value)
+				value = record.Value.String()
+			}
+			err = writer.Write([]string{
+				name,
+				strconv.FormatUint(record.UniqueID, 10),
+				record.Timestamp.Format(time.RFC3339Nano),
+				strconv.FormatInt(int64(record.Version), 10),
+				record.Direction(),
+				valueType,
+				value,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
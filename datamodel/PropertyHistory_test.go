@@ -0,0 +1,30 @@
+package datamodel
+
+import (
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+func TestPropertyHistoryForget(t *testing.T) {
+	history := NewPropertyHistory(2)
+	model, _ := NewInstance("Model", nil)
+	part, _ := NewInstance("Part", model)
+	other, _ := NewInstance("Part", nil)
+	for i := 0; i < 3; i++ {
+		for _, instance := range []*Instance{model, part, other} {
+			history.Record(instance, "Name", PropertyRecord{UniqueID: uint64(i), Value: rbxfile.ValueString("Part")})
+		}
+	}
+	if records := history.Get(part, "Name"); len(records) != 2 || records[0].UniqueID != 1 {
+		t.Errorf("history wasn't limited: %v", records)
+	}
+
+	history.Forget(model)
+	if len(history.records) != 1 || len(history.Properties(part)) != 0 {
+		t.Errorf("history of removed instances wasn't dropped: %d instances left", len(history.records))
+	}
+	if len(history.Get(other, "Name")) != 2 {
+		t.Error("history of another instance was dropped")
+	}
+}
@@ -10,26 +10,28 @@ import (
 // This prevents the conversion from creating a duplicate
 // rbxfile representation of an instance if it is visited twice
 // (e.g.) normally and via ValueReference
+//
+// The instances are identified by pointer, as the references
+// of different instances may have the same string representation.
 type RbxfileReferencePool struct {
-	pool map[string]*rbxfile.Instance
+	pool map[*Instance]*rbxfile.Instance
 }
 
 func NewRbxfileReferencePool() *RbxfileReferencePool {
-	return &RbxfileReferencePool{pool: make(map[string]*rbxfile.Instance)}
+	return &RbxfileReferencePool{pool: make(map[*Instance]*rbxfile.Instance)}
 }
 
 func (pool *RbxfileReferencePool) Make(instance *Instance) *rbxfile.Instance {
 	if instance == nil {
 		return nil
 	}
-	key := instance.Ref.String()
-	inst, ok := pool.pool[key]
+	inst, ok := pool.pool[instance]
 	if ok {
 		return inst
 	}
 	inst = rbxfile.NewInstance("", nil)
 
-	pool.pool[key] = inst
+	pool.pool[instance] = inst
 	return inst
 }
 
@@ -39,10 +41,15 @@ func (pool *RbxfileReferencePool) Make(instance *Instance) *rbxfile.Instance {
 // (e.g.) normally and via ValueReference
 type SelfReferencePool struct {
 	pool map[string]*Instance
+	// copies maps instances to their copies
+	copies map[*Instance]*Instance
 }
 
 func NewSelfReferencePool() *SelfReferencePool {
-	return &SelfReferencePool{pool: make(map[string]*Instance)}
+	return &SelfReferencePool{
+		pool:   make(map[string]*Instance),
+		copies: make(map[*Instance]*Instance),
+	}
 }
 
 func (pool *SelfReferencePool) Make(instance *rbxfile.Instance) *Instance {
@@ -63,6 +70,24 @@ func (pool *SelfReferencePool) MakeWithRef(ref string) *Instance {
 	return inst
 }
 
+// MakeCopy returns the copy of instance, creating an empty
+// instance if it hasn't been copied yet. The instances are identified
+// by pointer, as the references of different instances may have the same
+// string representation.
+func (pool *SelfReferencePool) MakeCopy(instance *Instance) *Instance {
+	if instance == nil {
+		return nil
+	}
+	inst, ok := pool.copies[instance]
+	if ok {
+		return inst
+	}
+	inst, _ = NewInstance("", nil)
+
+	pool.copies[instance] = inst
+	return inst
+}
+
 // ToRbxfile converts an Instance to the rbxfile format
 // Note: the parent of this instance must be assigned manually, however
 // its children will have their respective parents set correctly
@@ -0,0 +1,328 @@
+package datamodel
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/olebedev/emitter"
+	"github.com/robloxapi/rbxfile"
+)
+
+// referenceSlot reads and replaces a value that may hold an unresolved reference
+type referenceSlot struct {
+	get func() rbxfile.Value
+	set func(rbxfile.Value)
+	// valid returns false if the value containing the slot has been replaced.
+	// If it is nil, the slot is always valid.
+	valid func() bool
+}
+
+// referenceContainer describes the property or event invocation
+// whose value contains references
+type referenceContainer struct {
+	lock  *sync.RWMutex
+	valid func() bool
+}
+
+// sameContainer returns whether a and b are the same slice or map
+func sameContainer(a rbxfile.Value, b rbxfile.Value) bool {
+	valueA, valueB := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !valueA.IsValid() || !valueB.IsValid() || valueA.Type() != valueB.Type() {
+		return false
+	}
+	switch valueA.Kind() {
+	case reflect.Slice, reflect.Map:
+		return valueA.Pointer() == valueB.Pointer() && valueA.Len() == valueB.Len()
+	}
+	return false
+}
+
+// UnresolvedReference is a reference whose target instance didn't exist
+// when it was read. The reference is either the value of a property,
+// an event argument or a value nested inside one of them.
+type UnresolvedReference struct {
+	Instance *Instance
+	// Name is the name of the property or event
+	Name string
+	// IsEvent is true if the reference is an event argument
+	IsEvent bool
+	// Path describes where the reference is within the property value
+	// or event arguments, for example "[1]" or "[1][\"key\"]".
+	// It is empty for references that are property values.
+	Path      string
+	Reference Reference
+
+	slot      referenceSlot
+	arguments func() []rbxfile.Value
+}
+
+// Arguments returns a copy of the event arguments in which the references
+// resolved so far have been patched. It returns nil for properties.
+func (ref UnresolvedReference) Arguments() []rbxfile.Value {
+	if ref.arguments == nil {
+		return nil
+	}
+	return ref.arguments()
+}
+
+// copyValue copies the slices and maps that value consists of,
+// so that references nested in them can be patched independently
+func copyValue(value rbxfile.Value) rbxfile.Value {
+	switch value := value.(type) {
+	case ValueTuple:
+		return ValueTuple(copyValues(value))
+	case ValueArray:
+		return ValueArray(copyValues(value))
+	case ValueDictionary:
+		return ValueDictionary(copyValueMap(value))
+	case ValueMap:
+		return ValueMap(copyValueMap(value))
+	}
+	return value
+}
+
+func copyValues(values []rbxfile.Value) []rbxfile.Value {
+	if values == nil {
+		return nil
+	}
+	copied := make([]rbxfile.Value, len(values))
+	for i, value := range values {
+		copied[i] = copyValue(value)
+	}
+	return copied
+}
+
+func copyValueMap(values map[string]rbxfile.Value) map[string]rbxfile.Value {
+	copied := make(map[string]rbxfile.Value, len(values))
+	for key, value := range values {
+		copied[key] = copyValue(value)
+	}
+	return copied
+}
+
+// IsPending returns whether the value still holds the unresolved reference
+func (ref UnresolvedReference) IsPending() bool {
+	if ref.slot.valid != nil && !ref.slot.valid() {
+		return false
+	}
+	value, ok := ref.slot.get().(ValueReference)
+	return ok && value.IsUnresolved() && value.Reference.Equal(&ref.Reference)
+}
+
+func (ref UnresolvedReference) String() string {
+	return fmt.Sprintf("%s.%s%s -> %s", ref.Instance.GetFullName(), ref.Name, ref.Path, ref.Reference.String())
+}
+
+// unresolvedKey identifies the target of an unresolved reference
+// in the same way as InstanceList does
+type unresolvedKey struct {
+	peerID uint32
+	scope  string
+	id     uint32
+}
+
+func keyForReference(ref Reference) unresolvedKey {
+	if ref.PeerId != 0 {
+		return unresolvedKey{peerID: ref.PeerId, id: ref.Id}
+	}
+	return unresolvedKey{scope: ref.Scope, id: ref.Id}
+}
+
+// UnresolvedReferences keeps track of references that point to instances
+// that haven't been replicated yet. When the target instance is
+// created, the references are patched and the "resolved" event is emitted
+// on ResolveEmitter with the UnresolvedReference, the target and
+// the patched event arguments (nil for properties) as arguments.
+type UnresolvedReferences struct {
+	mutex          sync.Mutex
+	pending        map[unresolvedKey][]UnresolvedReference
+	ResolveEmitter *emitter.Emitter
+}
+
+// NewUnresolvedReferences returns an empty UnresolvedReferences registry
+func NewUnresolvedReferences() *UnresolvedReferences {
+	return &UnresolvedReferences{
+		pending:        make(map[unresolvedKey][]UnresolvedReference),
+		ResolveEmitter: emitter.New(1),
+	}
+}
+
+func (registry *UnresolvedReferences) add(ref UnresolvedReference) {
+	key := keyForReference(ref.Reference)
+	registry.mutex.Lock()
+	registry.pending[key] = append(registry.pending[key], ref)
+	registry.mutex.Unlock()
+}
+
+// addValue registers value if it is an unresolved reference, or
+// the unresolved references nested inside it
+func (registry *UnresolvedReferences) addValue(ref UnresolvedReference, value rbxfile.Value, slot referenceSlot, container referenceContainer) {
+	switch value := value.(type) {
+	case ValueReference:
+		if value.IsUnresolved() {
+			ref.Reference = value.Reference
+			ref.slot = slot
+			registry.add(ref)
+		}
+	case ValueTuple:
+		registry.addSlice(ref, value, container)
+	case ValueArray:
+		registry.addSlice(ref, value, container)
+	case ValueDictionary:
+		registry.addMap(ref, value, container)
+	case ValueMap:
+		registry.addMap(ref, value, container)
+	}
+}
+
+func (registry *UnresolvedReferences) addSlice(ref UnresolvedReference, values []rbxfile.Value, container referenceContainer) {
+	lock := container.lock
+	for i, value := range values {
+		i := i
+		element := ref
+		element.Path = fmt.Sprintf("%s[%d]", ref.Path, i)
+		registry.addValue(element, value, referenceSlot{
+			get: func() rbxfile.Value {
+				lock.RLock()
+				defer lock.RUnlock()
+				return values[i]
+			},
+			set: func(value rbxfile.Value) {
+				lock.Lock()
+				values[i] = value
+				lock.Unlock()
+			},
+			valid: container.valid,
+		}, container)
+	}
+}
+
+func (registry *UnresolvedReferences) addMap(ref UnresolvedReference, values map[string]rbxfile.Value, container referenceContainer) {
+	lock := container.lock
+	for key, value := range values {
+		key := key
+		element := ref
+		element.Path = fmt.Sprintf("%s[%q]", ref.Path, key)
+		registry.addValue(element, value, referenceSlot{
+			get: func() rbxfile.Value {
+				lock.RLock()
+				defer lock.RUnlock()
+				return values[key]
+			},
+			set: func(value rbxfile.Value) {
+				lock.Lock()
+				values[key] = value
+				lock.Unlock()
+			},
+			valid: container.valid,
+		}, container)
+	}
+}
+
+// Add registers the unresolved references in the value of the property.
+// Nested values such as Tuples and Arrays are searched too.
+// It is safe to call on a nil registry.
+func (registry *UnresolvedReferences) Add(instance *Instance, name string, value rbxfile.Value) {
+	if registry == nil {
+		return
+	}
+	ref := UnresolvedReference{Instance: instance, Name: name}
+	if _, ok := value.(ValueReference); ok {
+		// Patch the property using Set() so that listeners
+		// of PropertyEmitter are notified
+		registry.addValue(ref, value, referenceSlot{
+			get: func() rbxfile.Value { return instance.Get(name) },
+			set: func(value rbxfile.Value) { instance.Set(name, value) },
+		}, referenceContainer{})
+		return
+	}
+	registry.addValue(ref, value, referenceSlot{}, referenceContainer{
+		lock: instance.PropertiesMutex,
+		// References nested in a value that has been replaced are no longer pending
+		valid: func() bool { return sameContainer(instance.Get(name), value) },
+	})
+}
+
+// AddEventArguments registers the unresolved references in the arguments
+// of an event invocation. The arguments may be shared with the packet
+// and its listeners, so they are never modified: a copy of them is patched
+// instead, which is available through UnresolvedReference.Arguments().
+// It is safe to call on a nil registry.
+func (registry *UnresolvedReferences) AddEventArguments(instance *Instance, name string, arguments []rbxfile.Value) {
+	if registry == nil {
+		return
+	}
+	arguments = copyValues(arguments)
+	lock := &sync.RWMutex{}
+	ref := UnresolvedReference{
+		Instance: instance,
+		Name:     name,
+		IsEvent:  true,
+		arguments: func() []rbxfile.Value {
+			lock.RLock()
+			defer lock.RUnlock()
+			return copyValues(arguments)
+		},
+	}
+	registry.addSlice(ref, arguments, referenceContainer{lock: lock})
+}
+
+// Resolve patches the values that refer to target and
+// emits a "resolved" event for each of them. Values that have been
+// replaced since they were registered are left untouched.
+// It is safe to call on a nil registry.
+func (registry *UnresolvedReferences) Resolve(target *Instance) {
+	if registry == nil {
+		return
+	}
+	key := keyForReference(target.Ref)
+	registry.mutex.Lock()
+	refs := registry.pending[key]
+	delete(registry.pending, key)
+	registry.mutex.Unlock()
+
+	for _, ref := range refs {
+		if !ref.IsPending() {
+			continue
+		}
+		ref.slot.set(ValueReference{Instance: target, Reference: ref.Reference})
+		<-registry.ResolveEmitter.Emit("resolved", ref, target, ref.Arguments())
+	}
+}
+
+// Pending returns the references that haven't been resolved, sorted by
+// the name of the property's instance
+func (registry *UnresolvedReferences) Pending() []UnresolvedReference {
+	if registry == nil {
+		return nil
+	}
+	var pending []UnresolvedReference
+	registry.mutex.Lock()
+	for _, refs := range registry.pending {
+		for _, ref := range refs {
+			if ref.IsPending() {
+				pending = append(pending, ref)
+			}
+		}
+	}
+	registry.mutex.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].String() < pending[j].String()
+	})
+	return pending
+}
+
+// WriteReport writes a line to w for each reference that hasn't been resolved
+func (registry *UnresolvedReferences) WriteReport(w io.Writer) error {
+	for _, ref := range registry.Pending() {
+		_, err := fmt.Fprintln(w, ref.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
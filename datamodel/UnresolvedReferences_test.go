@@ -0,0 +1,86 @@
+package datamodel
+
+import (
+	"testing"
+
+	"github.com/olebedev/emitter"
+	"github.com/robloxapi/rbxfile"
+)
+
+func TestUnresolvedReferences(t *testing.T) {
+	registry := NewUnresolvedReferences()
+	targetRef := Reference{Scope: "RBXServer", Id: 9, PeerId: 2}
+	otherRef := Reference{Scope: "RBXServer", Id: 10, PeerId: 2}
+
+	model, _ := NewInstance("Model", nil)
+	model.Set("PrimaryPart", ValueReference{Reference: targetRef})
+	registry.Add(model, "PrimaryPart", model.Get("PrimaryPart"))
+
+	tuple := ValueTuple{rbxfile.ValueString("a"), ValueReference{Reference: targetRef}}
+	dictionary := ValueDictionary{"part": ValueReference{Reference: targetRef}}
+	model.Set("Tuple", tuple)
+	registry.Add(model, "Tuple", tuple)
+	model.Set("Dictionary", dictionary)
+	registry.Add(model, "Dictionary", dictionary)
+
+	arguments := []rbxfile.Value{ValueArray{ValueReference{Reference: targetRef}}}
+	registry.AddEventArguments(model, "OnClientEvent", arguments)
+
+	// Replaced before the target is created
+	model.Set("Replaced", ValueReference{Reference: targetRef})
+	registry.Add(model, "Replaced", model.Get("Replaced"))
+	model.Set("Replaced", ValueReference{Reference: NullReference})
+	replacedTuple := ValueTuple{ValueReference{Reference: targetRef}}
+	model.Set("ReplacedTuple", replacedTuple)
+	registry.Add(model, "ReplacedTuple", replacedTuple)
+	model.Set("ReplacedTuple", ValueTuple{})
+
+	other, _ := NewInstance("Model", nil)
+	other.Set("PrimaryPart", ValueReference{Reference: otherRef})
+	registry.Add(other, "PrimaryPart", other.Get("PrimaryPart"))
+
+	if pending := registry.Pending(); len(pending) != 5 {
+		t.Fatalf("expected 5 pending references, got %v", pending)
+	}
+
+	resolved := 0
+	var resolvedArguments []rbxfile.Value
+	registry.ResolveEmitter.On("resolved", func(e *emitter.Event) {
+		resolved++
+		if ref := e.Args[0].(UnresolvedReference); ref.IsEvent {
+			resolvedArguments = e.Args[2].([]rbxfile.Value)
+		}
+	}, emitter.Void)
+
+	target, _ := NewInstance("Part", nil)
+	// The scope doesn't matter if the peer ID is known
+	target.Ref = Reference{Scope: "RBXPID2", Id: 9, PeerId: 2}
+	registry.Resolve(target)
+
+	if resolved != 4 {
+		t.Errorf("expected 4 resolved events, got %d", resolved)
+	}
+	if model.Get("PrimaryPart").(ValueReference).Instance != target {
+		t.Error("property wasn't patched")
+	}
+	if tuple[1].(ValueReference).Instance != target {
+		t.Error("tuple wasn't patched")
+	}
+	if dictionary["part"].(ValueReference).Instance != target {
+		t.Error("dictionary wasn't patched")
+	}
+	if len(resolvedArguments) != 1 || resolvedArguments[0].(ValueArray)[0].(ValueReference).Instance != target {
+		t.Errorf("event argument wasn't patched: %v", resolvedArguments)
+	}
+	if arguments[0].(ValueArray)[0].(ValueReference).Instance != nil {
+		t.Error("event arguments of the packet were modified")
+	}
+	if !model.Get("Replaced").(ValueReference).Reference.IsNull {
+		t.Error("replaced property was patched")
+	}
+
+	pending := registry.Pending()
+	if len(pending) != 1 || pending[0].Instance != other || pending[0].Reference != otherRef {
+		t.Errorf("expected only the reference to %s to be pending, got %v", otherRef, pending)
+	}
+}
@@ -370,8 +370,19 @@ func (b *extendedReader) readCFrame() (rbxfile.ValueCFrame, error) {
 	return val, err
 }
 
-// TODO: Make this function uniform with other cache functions
-func (b *extendedReader) readSystemAddress() (datamodel.ValueSystemAddress, error) {
+// readSystemAddress reads a SystemAddress. In connections with
+// CapabilitySystemAddressIsPeerId, it is the peer ID of a player. Otherwise
+// it is an IPv4 address followed by a port, which are returned as
+// address<<16 | port.
+func (b *extendedReader) readSystemAddress(profile ProtocolProfile) (datamodel.ValueSystemAddress, error) {
+	if !profile.HasCapability(CapabilitySystemAddressIsPeerId) {
+		address, err := b.readUint32BE()
+		if err != nil {
+			return datamodel.ValueSystemAddress(0), err
+		}
+		port, err := b.readUint16BE()
+		return datamodel.ValueSystemAddress(uint64(address)<<16 | uint64(port)), err
+	}
 	val, err := b.readVarint64()
 	if err != nil {
 		return datamodel.ValueSystemAddress(0), err
@@ -456,7 +467,40 @@ func (b *extendedReader) readNewPString(caches *Caches) (rbxfile.ValueString, er
 	return rbxfile.ValueString(val), err
 }
 
+// readInlineProtectedString reads the bytecode and signature of a script
+// from peers that don't replicate scripts as shared strings
+func (b *extendedReader) readInlineProtectedString(profile ProtocolProfile) (datamodel.ValueSignedProtectedString, error) {
+	bytecodeLen, err := b.readUintUTF8()
+	if err != nil {
+		return datamodel.ValueSignedProtectedString{}, err
+	}
+	bytecode, err := b.readString(int(bytecodeLen))
+	if err != nil {
+		return datamodel.ValueSignedProtectedString{}, err
+	}
+	signatureLen, err := b.readUintUTF8()
+	if err != nil {
+		return datamodel.ValueSignedProtectedString{}, err
+	}
+	signature, err := b.readString(int(signatureLen))
+	if err != nil {
+		return datamodel.ValueSignedProtectedString{}, err
+	}
+
+	value := rbxfile.ValueSharedString(bytecode)
+	return datamodel.ValueSignedProtectedString{
+		Value: &datamodel.ValueDeferredString{
+			Hash:  profile.sharedStringHash(value),
+			Value: value,
+		},
+		Signature: signature,
+	}, nil
+}
+
 func (b *extendedReader) readLuauProtectedString(deferred deferredStrings) (datamodel.ValueSignedProtectedString, error) {
+	if !deferred.profile.HasCapability(CapabilityReplicateLuau) {
+		return b.readInlineProtectedString(deferred.profile)
+	}
 	return b.readLuauProtectedStringRaw(deferred)
 }
 
@@ -493,6 +537,9 @@ func (b *extendedReader) readNewBinaryString() (rbxfile.ValueBinaryString, error
 func (b *extendedReader) readNewEnumValue(enumID uint16, context *CommunicationContext) (datamodel.ValueToken, error) {
 	val, err := b.readUintUTF8()
 	token := datamodel.ValueToken{Value: val, ID: enumID}
+	if context.NetworkSchema != nil {
+		token = context.NetworkSchema.NameToken(token)
+	}
 	return token, err
 }
 
@@ -1091,7 +1138,7 @@ func (b *extendedReader) readSerializedValueGeneric(reader PacketReader, valueTy
 	case PropertyTypeComplicatedCFrame:
 		result, err = b.readCFrame()
 	case PropertyTypeSystemAddress:
-		result, err = b.readSystemAddress()
+		result, err = b.readSystemAddress(reader.Context().Profile())
 	case PropertyTypeNumberSequence:
 		result, err = b.readNumberSequence()
 	case PropertyTypeNumberSequenceKeypoint:
@@ -1132,9 +1179,12 @@ func (b *extendedReader) readSerializedValueGeneric(reader PacketReader, valueTy
 		if reference.IsNull {
 			result = datamodel.ValueReference{Instance: nil, Reference: reference}
 		} else {
-			// CreateInstance: allow forward references
+			// Forward references are left unresolved until the instance is created
 			var instance *datamodel.Instance
-			instance, err = reader.Context().InstancesByReference.CreateInstance(reference)
+			instance, err = reader.Context().InstancesByReference.TryGetInstance(reference)
+			if err == datamodel.ErrInstanceDoesntExist {
+				err = nil
+			}
 			result = datamodel.ValueReference{Instance: instance, Reference: reference}
 		}
 	default:
@@ -2,8 +2,10 @@ package peer
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/robloxapi/rbxapi/rbxapijson"
 	"github.com/robloxapi/rbxfile"
 )
 
@@ -75,6 +77,9 @@ type CommunicationContext struct {
 	InstancesByReference *datamodel.InstanceList
 	// NetworkSchema is the network instance/enum schema used in this communication
 	NetworkSchema *NetworkSchema
+	// APIDump is an optional Roblox API dump. If it is set, enum item names
+	// will be loaded from it when the NetworkSchema is received.
+	APIDump *rbxapijson.Root
 	// IsStudio
 	IsStudio bool
 	// SharedStrings contains a dictionary of SharedStrings indexed by their MD5 hash
@@ -89,6 +94,19 @@ type CommunicationContext struct {
 	PlaceID   int64
 	VersionID Packet90VersionID
 
+	// profile describes the protocol version and features negotiated
+	// in this communication. It is read by the goroutines of
+	// PacketLogicHandler, so it is guarded by profileMutex.
+	profile      ProtocolProfile
+	profileMutex sync.RWMutex
+
+	// Journal records the changes made to the DataModel by the default
+	// DataModel handlers. If it is nil, no changes are recorded.
+	Journal *ReplicationJournal
+	// UnresolvedReferences contains the reference properties whose
+	// target instance hasn't been replicated yet
+	UnresolvedReferences *datamodel.UnresolvedReferences
+
 	uniqueID uint64
 }
 
@@ -97,6 +115,7 @@ func NewCommunicationContext() *CommunicationContext {
 	return &CommunicationContext{
 		DataModel:            datamodel.New(),
 		InstancesByReference: datamodel.NewInstanceList(),
+		UnresolvedReferences: datamodel.NewUnresolvedReferences(),
 	}
 }
 
@@ -114,6 +133,37 @@ func (context *CommunicationContext) GenerateSubmitTicketKey() [0x10]byte {
 	return result
 }
 
+// Profile returns a copy of the protocol profile of the communication
+func (context *CommunicationContext) Profile() ProtocolProfile {
+	context.profileMutex.RLock()
+	defer context.profileMutex.RUnlock()
+	return context.profile
+}
+
+// updateProfile calls update with the protocol profile while holding its lock
+func (context *CommunicationContext) updateProfile(update func(*ProtocolProfile)) {
+	context.profileMutex.Lock()
+	update(&context.profile)
+	context.profileMutex.Unlock()
+}
+
+// ServerScope is the scope name of references created by the server
+const ServerScope = "RBXServer"
+
+// ReferenceScope returns the scope name of references created by the peer.
+// References are resolved by their peer ID, so the scope name is
+// only used for display.
+func (context *CommunicationContext) ReferenceScope(peerID uint32) string {
+	if peerID == context.ServerPeerID {
+		return ServerScope
+	}
+	return fmt.Sprintf("RBXPID%d", peerID)
+}
+
+// removeInstance removes the instance and its descendants from
+// InstancesByReference and the property history and destroys them
 func (context *CommunicationContext) removeInstance(instance *datamodel.Instance) {
 	context.InstancesByReference.RemoveTree(instance)
+	context.DataModel.History.Forget(instance)
+	instance.Destroy()
 }
@@ -21,6 +21,71 @@ type extendedReader struct {
 	r io.Reader
 }
 
+// ExtendedReader is the stream passed to packet decoders. Packets that are
+// registered outside of this package can read from it using Read(), ReadByte()
+// and the exported Read* helpers below.
+type ExtendedReader = extendedReader
+
+// ReadUint8 reads one byte
+func (b *extendedReader) ReadUint8() (uint8, error) {
+	return b.readUint8()
+}
+
+// ReadBool reads a byte that must be either 0 or 1
+func (b *extendedReader) ReadBool() (bool, error) {
+	return b.readBoolByte()
+}
+
+// ReadUint16BE reads a big-endian uint16
+func (b *extendedReader) ReadUint16BE() (uint16, error) {
+	return b.readUint16BE()
+}
+
+// ReadUint32BE reads a big-endian uint32
+func (b *extendedReader) ReadUint32BE() (uint32, error) {
+	return b.readUint32BE()
+}
+
+// ReadUint32LE reads a little-endian uint32
+func (b *extendedReader) ReadUint32LE() (uint32, error) {
+	return b.readUint32LE()
+}
+
+// ReadUint64BE reads a big-endian uint64
+func (b *extendedReader) ReadUint64BE() (uint64, error) {
+	return b.readUint64BE()
+}
+
+// ReadFloat32BE reads a big-endian float32
+func (b *extendedReader) ReadFloat32BE() (float32, error) {
+	return b.readFloat32BE()
+}
+
+// ReadFloat64BE reads a big-endian float64
+func (b *extendedReader) ReadFloat64BE() (float64, error) {
+	return b.readFloat64BE()
+}
+
+// ReadVarint64 reads an unsigned LEB128 varint
+func (b *extendedReader) ReadVarint64() (uint64, error) {
+	return b.readVarint64()
+}
+
+// ReadString reads length bytes
+func (b *extendedReader) ReadString(length int) ([]byte, error) {
+	return b.readString(length)
+}
+
+// ReadVarLengthString reads a string prefixed by its varint length
+func (b *extendedReader) ReadVarLengthString() (string, error) {
+	return b.readVarLengthString()
+}
+
+// ReadObject reads an instance reference in the format used by ID_DATA subpackets
+func (b *extendedReader) ReadObject(context *CommunicationContext) (datamodel.Reference, error) {
+	return b.readObject(context)
+}
+
 func (b *extendedReader) ReadByte() (byte, error) {
 	var byt [1]byte
 	n, err := b.r.Read(byt[:])
@@ -185,11 +250,8 @@ func (b *extendedReader) readObject(context *CommunicationContext) (datamodel.Re
 		ref.Scope = "null"
 		return ref, err
 		// Approximately reflects handling in client?
-	} else if uint32(peerID) == context.ServerPeerID {
-		ref.Scope = "RBXServer"
-	} else {
-		ref.Scope = fmt.Sprintf("RBXPID%d", peerID)
 	}
+	ref.Scope = context.ReferenceScope(ref.PeerId)
 	ref.Id, err = b.readUint32LE()
 	if err != nil {
 		return ref, err
@@ -379,12 +441,15 @@ func (b *extendedReader) readReliabilityFlags() (uint8, bool, error) {
 type deferredStrings struct {
 	m                    map[string][]*datamodel.ValueDeferredString
 	underlyingDictionary map[string]rbxfile.ValueSharedString
+	// profile decides how scripts are read
+	profile ProtocolProfile
 }
 
 func newDeferredStrings(reader PacketReader) deferredStrings {
 	return deferredStrings{
 		m:                    make(map[string][]*datamodel.ValueDeferredString),
 		underlyingDictionary: reader.SharedStrings(),
+		profile:              reader.Context().Profile(),
 	}
 }
 
@@ -409,6 +474,8 @@ func (m deferredStrings) Resolve(md5 string, value rbxfile.ValueSharedString) {
 type writeDeferredStrings struct {
 	m                    map[string]rbxfile.ValueSharedString
 	underlyingDictionary map[string]rbxfile.ValueSharedString
+	// profile decides how scripts and hashes are written
+	profile ProtocolProfile
 }
 
 func (b *extendedReader) resolveDeferredStrings(defers deferredStrings) error {
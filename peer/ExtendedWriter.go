@@ -20,6 +20,10 @@ type extendedWriter struct {
 	w io.Writer
 }
 
+// ExtendedWriter is the stream passed to packet serializers. Packets that are
+// registered outside of this package can write to it using Write() and WriteByte().
+type ExtendedWriter = extendedWriter
+
 func (b *extendedWriter) bytes(length int, value []byte) error {
 	if length > len(value) {
 		return errors.New("buffer overflow")
@@ -191,6 +195,15 @@ func (b *extendedWriter) writeObject(object *datamodel.Instance, context *Commun
 	}
 	return b.writeRefPeerID(object.Ref, context)
 }
+
+// writeReference writes a reference value. Unresolved references are
+// written as they were read.
+func (b *extendedWriter) writeReference(value datamodel.ValueReference, context *CommunicationContext) error {
+	if value.IsUnresolved() {
+		return b.writeRefPeerID(value.Reference, context)
+	}
+	return b.writeObject(value.Instance, context)
+}
 func (b *extendedWriter) writeRefPeerID(ref datamodel.Reference, context *CommunicationContext) error {
 	if ref.IsNull {
 		return b.writeVarint64(0)
@@ -344,17 +357,31 @@ func newWriteDeferredStrings(writer PacketWriter) writeDeferredStrings {
 	return writeDeferredStrings{
 		m:                    make(map[string]rbxfile.ValueSharedString),
 		underlyingDictionary: writer.SharedStrings(),
+		profile:              writer.Context().Profile(),
+	}
+}
+
+// hashOf returns the hash of the deferred string. Strings that weren't read
+// from the network, such as the ones read from a project, don't have a hash
+// yet, so they are hashed using the algorithm of the connection.
+func (m writeDeferredStrings) hashOf(value *datamodel.ValueDeferredString) (string, error) {
+	if value.Hash == "" {
+		return m.profile.sharedStringHash(value.Value), nil
+	}
+	if len(value.Hash) != sharedStringHashSize {
+		return "", errors.New("invalid deferred hash")
 	}
+	return value.Hash, nil
 }
 
-func (m writeDeferredStrings) Defer(value *datamodel.ValueDeferredString) {
+func (m writeDeferredStrings) Defer(hash string, value rbxfile.ValueSharedString) {
 	// if previously sent in *another packet*, we're not expected to defer it
-	if _, ok := m.underlyingDictionary[value.Hash]; ok {
+	if _, ok := m.underlyingDictionary[hash]; ok {
 		return
 	}
 	// if duplicated within the same packet, overwritten -- doesn't matter
 	// otherwise just add it to the defers
-	m.m[value.Hash] = value.Value
+	m.m[hash] = value
 }
 
 func (b *extendedWriter) resolveDeferredStrings(defers writeDeferredStrings) error {
@@ -148,6 +148,12 @@ func (thisStream *extendedReader) DecodePacket05Layer(reader PacketReader, layer
 	var err error
 	layer := &Packet05Layer{}
 	layer.ProtocolVersion, err = thisStream.readUint8() // !! RakNetLayer will have read the offline message !!
+	if err != nil {
+		return layer, err
+	}
+	reader.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.RakNetVersion = layer.ProtocolVersion
+	})
 	mtupad, err := ioutil.ReadAll(thisStream)
 	if err != nil {
 		return layer, err
@@ -250,12 +256,18 @@ func (thisStream *extendedReader) DecodePacket07Layer(reader PacketReader, layer
 	if err != nil {
 		return layer, err
 	}
-	return layer, err
+	reader.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.recordOpenConnectionRequest(layer)
+	})
+	return layer, nil
 }
 
 // Serialize implements RakNetPacket.Serialize()
 func (layer *Packet07Layer) Serialize(writer PacketWriter, stream *extendedWriter) error {
 	var err error
+	writer.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.recordOpenConnectionRequest(layer)
+	})
 	err = stream.writeAddress(layer.IPAddress)
 	if err != nil {
 		return err
@@ -316,12 +328,18 @@ func (thisStream *extendedReader) DecodePacket08Layer(reader PacketReader, layer
 	if err != nil {
 		return layer, err
 	}
-	return layer, err
+	reader.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.recordOpenConnectionReply(layer)
+	})
+	return layer, nil
 }
 
 // Serialize implements RakNetPacket.Serialize()
 func (layer *Packet08Layer) Serialize(writer PacketWriter, stream *extendedWriter) error {
 	var err error
+	writer.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.recordOpenConnectionReply(layer)
+	})
 	err = stream.writeUint64BE(layer.GUID)
 	if err != nil {
 		return err
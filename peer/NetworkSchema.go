@@ -1,5 +1,11 @@
 package peer
 
+import (
+	"fmt"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+)
+
 const (
 	// PropertyTypeNil is the type for nil values
 	PropertyTypeNil uint8 = iota
@@ -160,6 +166,15 @@ var TypeNames = map[uint8]string{
 	PropertyTypeOptimizedString:        "string (optimized)",
 }
 
+// typeString returns the name of the type, including for unknown types
+func typeString(thisType uint8) string {
+	name, ok := TypeNames[thisType]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown %d", thisType)
+}
+
 // NetworkArgumentSchema describes the schema of one event argument
 type NetworkArgumentSchema struct {
 	Type       uint8
@@ -167,11 +182,31 @@ type NetworkArgumentSchema struct {
 	EnumID     uint16
 }
 
+// NetworkEnumItemSchema describes one item of an enum
+type NetworkEnumItemSchema struct {
+	Name  string
+	Value uint32
+}
+
 // NetworkEnumSchema describes the schema of one enum
 type NetworkEnumSchema struct {
 	Name      string
 	BitSize   uint8
 	NetworkID uint16
+	// Items is not part of the network schema. It must be loaded
+	// separately, for example using LoadEnumItems()
+	Items []*NetworkEnumItemSchema
+}
+
+// ItemName finds the name of the enum item with the given value
+// If the item is unknown, it returns an empty string
+func (schema *NetworkEnumSchema) ItemName(value uint32) string {
+	for _, item := range schema.Items {
+		if item.Value == value {
+			return item.Name
+		}
+	}
+	return ""
 }
 
 // NetworkEventSchema describes the schema of one event
@@ -259,6 +294,18 @@ func (schema *NetworkSchema) SchemaForEnum(enum string) *NetworkEnumSchema {
 	return nil
 }
 
+// NameToken fills in the enum and item names of a token based on its
+// enum ID and value
+func (schema *NetworkSchema) NameToken(token datamodel.ValueToken) datamodel.ValueToken {
+	if int(token.ID) >= len(schema.Enums) {
+		return token
+	}
+	enum := schema.Enums[token.ID]
+	token.EnumName = enum.Name
+	token.ItemName = enum.ItemName(token.Value)
+	return token
+}
+
 // NetworkSchema represents the data serialization schema
 // and class/enum API for a communication as specified by the server
 type NetworkSchema struct {
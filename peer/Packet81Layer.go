@@ -71,6 +71,11 @@ func (thisStream *extendedReader) DecodePacket81Layer(reader PacketReader, layer
 	layer.PeerID = uint32(peerID)
 
 	reader.Context().ServerPeerID = layer.PeerID
+	// Instances may have been referred to before the server's peer ID was known
+	reader.Context().InstancesByReference.BindPeerScope(layer.PeerID, ServerScope)
+	reader.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.StreamJob = layer.StreamJob
+	})
 	if !reader.Context().IsStudio {
 		layer.ScriptKey, err = thisStream.readUint32BE()
 		if err != nil {
@@ -138,6 +143,9 @@ func (thisStream *extendedReader) DecodePacket81Layer(reader PacketReader, layer
 // Serialize implements RakNetPacket.Serialize()
 func (layer *Packet81Layer) Serialize(writer PacketWriter, stream *extendedWriter) error {
 	var err error
+	writer.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.StreamJob = layer.StreamJob
+	})
 
 	err = stream.writeBoolByte(layer.StreamJob)
 	if err != nil {
@@ -6,7 +6,8 @@ import (
 	"strconv"
 )
 
-// Packet83Subpackets containts a list of string names for all 0x83 subpackets
+// Packet83Subpackets containts a list of string names for all 0x83 subpackets.
+// Use Packet83SubpacketName() to read it while decoders may be registered.
 var Packet83Subpackets = map[uint8]string{
 	0x00: "ID_REPLIC_END",
 	0x01: "ID_REPLIC_DELETE_INSTANCE",
@@ -31,7 +32,7 @@ var Packet83Subpackets = map[uint8]string{
 	0x14: "ID_REPLIC_STREAM_DATA_INFO",
 }
 
-var packet83Decoders = map[uint8](func(*extendedReader, PacketReader, *PacketLayers) (Packet83Subpacket, error)){
+var packet83Decoders = map[uint8]Packet83Decoder{
 	0x01: (*extendedReader).DecodePacket83_01,
 	0x02: (*extendedReader).DecodePacket83_02,
 	0x03: (*extendedReader).DecodePacket83_03,
@@ -39,6 +40,7 @@ var packet83Decoders = map[uint8](func(*extendedReader, PacketReader, *PacketLay
 	0x05: (*extendedReader).DecodePacket83_05,
 	0x06: (*extendedReader).DecodePacket83_06,
 	0x07: (*extendedReader).DecodePacket83_07,
+	0x08: (*extendedReader).DecodePacket83_08,
 	0x09: (*extendedReader).DecodePacket83_09,
 	0x0A: (*extendedReader).DecodePacket83_0A,
 	0x0B: (*extendedReader).DecodePacket83_0B,
@@ -78,13 +80,21 @@ func (thisStream *extendedReader) DecodePacket83Layer(reader PacketReader, layer
 	var inner Packet83Subpacket
 	for packetType != 0 {
 		//println("parsing subpacket", packetType)
-		decoder, ok := packet83Decoders[packetType]
+		decoder, ok := packet83Decoder(packetType)
 		if !ok {
-			return layer, errors.New("don't know how to parse replication subpacket: " + strconv.Itoa(int(packetType)))
+			// The length of an unknown subpacket can't be determined,
+			// so the rest of the packet is kept as-is
+			inner, err = thisStream.decodeOpaqueSubpacket(packetType)
+			if err != nil {
+				return layer, errors.New("parsing unknown subpacket " + strconv.Itoa(int(packetType)) + ": " + err.Error())
+			}
+			layer.SubPackets = append(layer.SubPackets, inner)
+			return layer, nil
 		}
 		inner, err = decoder(thisStream, reader, layers)
 		if err != nil {
-			return layer, errors.New("parsing subpacket " + Packet83Subpackets[packetType] + ": " + err.Error())
+			name, _ := Packet83SubpacketName(packetType)
+			return layer, errors.New("parsing subpacket " + name + ": " + err.Error())
 		}
 
 		layer.SubPackets = append(layer.SubPackets, inner)
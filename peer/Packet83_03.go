@@ -121,7 +121,7 @@ func (layer *Packet83_03) Serialize(writer PacketWriter, stream *extendedWriter)
 			}
 		}
 
-		return stream.writeObject(layer.Value.(datamodel.ValueReference).Instance, writer.Context())
+		return stream.writeReference(layer.Value.(datamodel.ValueReference), writer.Context())
 	}
 
 	err = stream.writeUint16BE(layer.Schema.NetworkID)
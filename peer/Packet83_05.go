@@ -1,9 +1,14 @@
 package peer
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Packet83_05 represents ID_PING
 type Packet83_05 struct {
+	// PacketVersion is the format of the packet. It is always 0 in
+	// connections older than ProtocolVersionVersionedPing.
 	PacketVersion uint8
 	// Always false
 	Timestamp uint64
@@ -20,7 +25,13 @@ func (thisStream *extendedReader) DecodePacket83_05(reader PacketReader, layers
 	var err error
 	inner := &Packet83_05{}
 
-	inner.PacketVersion, err = thisStream.readUint8()
+	profile := reader.Context().Profile()
+	if profile.versionedPing() {
+		inner.PacketVersion, err = thisStream.readUint8()
+	} else {
+		// Older peers send IsPingBack, which is always false
+		_, err = thisStream.readBoolByte()
+	}
 	if err != nil {
 		return inner, err
 	}
@@ -56,6 +67,9 @@ func (thisStream *extendedReader) DecodePacket83_05(reader PacketReader, layers
 	} else {
 		return inner, errors.New("invalid packetversion")
 	}
+	reader.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.PingVersion = inner.PacketVersion
+	})
 	inner.SendStats, err = thisStream.readUint32BE()
 	if err != nil {
 		return inner, err
@@ -64,9 +78,7 @@ func (thisStream *extendedReader) DecodePacket83_05(reader PacketReader, layers
 	if err != nil {
 		return inner, err
 	}
-	if inner.Timestamp&0x20 != 0 {
-		inner.ExtraStats ^= 0xFFFFFFFF
-	}
+	inner.ExtraStats ^= profile.extraStatsMask(inner.Timestamp)
 
 	return inner, err
 }
@@ -74,7 +86,14 @@ func (thisStream *extendedReader) DecodePacket83_05(reader PacketReader, layers
 // Serialize implements Packet83Subpacket.Serialize()
 func (layer *Packet83_05) Serialize(writer PacketWriter, stream *extendedWriter) error {
 	var err error
-	err = stream.WriteByte(layer.PacketVersion)
+	profile := writer.Context().Profile()
+	if profile.versionedPing() {
+		err = stream.WriteByte(layer.PacketVersion)
+	} else if layer.PacketVersion <= 1 {
+		err = stream.writeBoolByte(false)
+	} else {
+		return fmt.Errorf("ping version %d requires protocol version %d", layer.PacketVersion, ProtocolVersionVersionedPing)
+	}
 	if err != nil {
 		return err
 	}
@@ -111,11 +130,7 @@ func (layer *Packet83_05) Serialize(writer PacketWriter, stream *extendedWriter)
 	if err != nil {
 		return err
 	}
-	if layer.Timestamp&0x20 != 0 {
-		layer.ExtraStats ^= 0xFFFFFFFF
-	}
-
-	err = stream.writeUint32BE(layer.ExtraStats)
+	err = stream.writeUint32BE(layer.ExtraStats ^ profile.extraStatsMask(layer.Timestamp))
 	return err
 }
 
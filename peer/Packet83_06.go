@@ -31,9 +31,8 @@ func (thisStream *extendedReader) DecodePacket83_06(reader PacketReader, layers
 	if err != nil {
 		return inner, err
 	}
-	if inner.Timestamp&0x20 != 0 {
-		inner.ExtraStats ^= 0xFFFFFFFF
-	}
+	profile := reader.Context().Profile()
+	inner.ExtraStats ^= profile.extraStatsMask(inner.Timestamp)
 
 	return inner, err
 }
@@ -53,11 +52,8 @@ func (layer *Packet83_06) Serialize(writer PacketWriter, stream *extendedWriter)
 	if err != nil {
 		return err
 	}
-	if layer.Timestamp&0x20 != 0 {
-		layer.ExtraStats ^= 0xFFFFFFFF
-	}
-
-	err = stream.writeUint32BE(layer.ExtraStats)
+	profile := writer.Context().Profile()
+	err = stream.writeUint32BE(layer.ExtraStats ^ profile.extraStatsMask(layer.Timestamp))
 	return err
 }
 
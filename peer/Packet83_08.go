@@ -0,0 +1,44 @@
+package peer
+
+import (
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+)
+
+// Packet83_08 represents ID_REPLIC_REQUEST_CHAR
+// It is sent by the client when it's ready for its character
+// to be spawned.
+type Packet83_08 struct {
+	Player *datamodel.Instance
+}
+
+func (thisStream *extendedReader) DecodePacket83_08(reader PacketReader, layers *PacketLayers) (Packet83Subpacket, error) {
+	var err error
+	inner := &Packet83_08{}
+
+	reference, err := thisStream.readObject(reader.Context())
+	if err != nil {
+		return inner, err
+	}
+	inner.Player, err = reader.Context().InstancesByReference.TryGetInstance(reference)
+
+	return inner, err
+}
+
+// Serialize implements Packet83Subpacket.Serialize()
+func (layer *Packet83_08) Serialize(writer PacketWriter, stream *extendedWriter) error {
+	return stream.writeObject(layer.Player, writer.Context())
+}
+
+// Type implements Packet83Subpacket.Type()
+func (Packet83_08) Type() uint8 {
+	return 8
+}
+
+// TypeString implements Packet83Subpacket.TypeString()
+func (Packet83_08) TypeString() string {
+	return "ID_REPLIC_REQUEST_CHAR"
+}
+
+func (layer *Packet83_08) String() string {
+	return "ID_REPLIC_REQUEST_CHAR: " + layer.Player.GetFullName()
+}
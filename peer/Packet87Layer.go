@@ -12,42 +12,54 @@ type Packet87Layer struct {
 	Message  string
 }
 
-func (thisStream *extendedReader) DecodePacket87Layer(reader PacketReader, layers *PacketLayers) (RakNetPacket, error) {
-	context := reader.Context()
-	layer := &Packet87Layer{}
-	var ref datamodel.Reference
-
+// readChatPlayer reads a player reference in the format used by chat packets
+func (thisStream *extendedReader) readChatPlayer(reader PacketReader) (*datamodel.Instance, error) {
 	peerID, err := thisStream.readVarint64()
 	if err != nil {
-		return layer, err
+		return nil, err
 	}
 	id, err := thisStream.readUint32BE() // Yes, big-endian
 	if err != nil {
-		return layer, err
+		return nil, err
 	}
 
 	// This reference will never be null
-	ref = datamodel.Reference{Scope: fmt.Sprintf("RBXPID%d", peerID), Id: id, PeerId: uint32(peerID)}
-	layer.Instance, err = context.InstancesByReference.TryGetInstance(ref)
+	context := reader.Context()
+	ref := datamodel.Reference{Scope: context.ReferenceScope(uint32(peerID)), Id: id, PeerId: uint32(peerID)}
+	return context.InstancesByReference.TryGetInstance(ref)
+}
+
+func (thisStream *extendedReader) readChatMessage() (string, error) {
+	messageLen, err := thisStream.readUint32BE()
 	if err != nil {
-		return layer, err
+		return "", err
+	}
+	return thisStream.readASCII(int(messageLen))
+}
+
+func (stream *extendedWriter) writeChatPlayer(player *datamodel.Instance) error {
+	err := stream.writeVarint64(uint64(player.Ref.PeerId))
+	if err != nil {
+		return err
 	}
+	return stream.writeUint32BE(player.Ref.Id)
+}
 
-	messageLen, err := thisStream.readUint32BE()
+func (thisStream *extendedReader) DecodePacket87Layer(reader PacketReader, layers *PacketLayers) (RakNetPacket, error) {
+	layer := &Packet87Layer{}
+	var err error
+
+	layer.Instance, err = thisStream.readChatPlayer(reader)
 	if err != nil {
 		return layer, err
 	}
-	layer.Message, err = thisStream.readASCII(int(messageLen))
+	layer.Message, err = thisStream.readChatMessage()
 	return layer, err
 }
 
 // Serialize implements RakNetPacket.Serialize
 func (layer *Packet87Layer) Serialize(writer PacketWriter, stream *extendedWriter) error {
-	err := stream.writeVarint64(uint64(layer.Instance.Ref.PeerId))
-	if err != nil {
-		return err
-	}
-	err = stream.writeUint32BE(layer.Instance.Ref.Id)
+	err := stream.writeChatPlayer(layer.Instance)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,48 @@
+package peer
+
+import (
+	"fmt"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+)
+
+// Packet88Layer represents ID_CHAT_TEAM
+type Packet88Layer struct {
+	Instance *datamodel.Instance
+	Message  string
+}
+
+func (thisStream *extendedReader) DecodePacket88Layer(reader PacketReader, layers *PacketLayers) (RakNetPacket, error) {
+	layer := &Packet88Layer{}
+	var err error
+
+	layer.Instance, err = thisStream.readChatPlayer(reader)
+	if err != nil {
+		return layer, err
+	}
+	layer.Message, err = thisStream.readChatMessage()
+	return layer, err
+}
+
+// Serialize implements RakNetPacket.Serialize
+func (layer *Packet88Layer) Serialize(writer PacketWriter, stream *extendedWriter) error {
+	err := stream.writeChatPlayer(layer.Instance)
+	if err != nil {
+		return err
+	}
+	return stream.writeUint32AndString(layer.Message)
+}
+
+func (layer *Packet88Layer) String() string {
+	return fmt.Sprintf("ID_CHAT_TEAM: <%s>", layer.Instance.GetFullName())
+}
+
+// TypeString implements RakNetPacket.TypeString()
+func (Packet88Layer) TypeString() string {
+	return "ID_CHAT_TEAM"
+}
+
+// Type implements RakNetPacket.Type()
+func (Packet88Layer) Type() byte {
+	return 0x88
+}
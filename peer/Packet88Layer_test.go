@@ -0,0 +1,56 @@
+package peer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+)
+
+// chatTestPlayers returns a reader and a writer sharing a context
+// in which the given number of players have been replicated
+func chatTestPlayers(count int) (*DefaultPacketReader, *DefaultPacketWriter, []*datamodel.Instance) {
+	context := NewCommunicationContext()
+	players := make([]*datamodel.Instance, count)
+	for i := range players {
+		players[i], _ = datamodel.NewInstance("Player", nil)
+		players[i].Ref = datamodel.Reference{Scope: "RBXPID2", Id: uint32(i + 1), PeerId: 2}
+		context.InstancesByReference.AddInstance(players[i].Ref, players[i])
+	}
+	reader := NewPacketReader()
+	reader.SetContext(context)
+	writer := NewPacketWriter()
+	writer.SetContext(context)
+	return reader, writer, players
+}
+
+// testChatRoundTrip checks that decoding the serialized packet
+// results in the same packet
+func testChatRoundTrip(t *testing.T, reader PacketReader, writer PacketWriter, packet RakNetPacket, decode func(*extendedReader, PacketReader, *PacketLayers) (RakNetPacket, error)) {
+	t.Helper()
+	var output bytes.Buffer
+	err := packet.Serialize(writer, &extendedWriter{&output})
+	if err != nil {
+		t.Fatalf("failed to serialize %s: %s", packet.TypeString(), err.Error())
+	}
+	source := bytes.NewReader(output.Bytes())
+	decoded, err := decode(&extendedReader{source}, reader, &PacketLayers{})
+	if err != nil {
+		t.Fatalf("failed to decode %s: %s", packet.TypeString(), err.Error())
+	}
+	if !reflect.DeepEqual(decoded, packet) {
+		t.Errorf("%s changed in a round trip: %+v, expected %+v", packet.TypeString(), decoded, packet)
+	}
+	if source.Len() != 0 {
+		t.Errorf("%d bytes of %s weren't read", source.Len(), packet.TypeString())
+	}
+}
+
+func TestPacket88RoundTrip(t *testing.T) {
+	reader, writer, players := chatTestPlayers(1)
+	testChatRoundTrip(t, reader, writer, &Packet88Layer{
+		Instance: players[0],
+		Message:  "hello team",
+	}, (*extendedReader).DecodePacket88Layer)
+}
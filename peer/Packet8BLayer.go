@@ -0,0 +1,38 @@
+package peer
+
+import (
+	"fmt"
+)
+
+// Packet8BLayer represents ID_CHAT_GAME
+// It is sent by the server to display a system message.
+type Packet8BLayer struct {
+	Message string
+}
+
+func (thisStream *extendedReader) DecodePacket8BLayer(reader PacketReader, layers *PacketLayers) (RakNetPacket, error) {
+	layer := &Packet8BLayer{}
+	var err error
+
+	layer.Message, err = thisStream.readChatMessage()
+	return layer, err
+}
+
+// Serialize implements RakNetPacket.Serialize
+func (layer *Packet8BLayer) Serialize(writer PacketWriter, stream *extendedWriter) error {
+	return stream.writeUint32AndString(layer.Message)
+}
+
+func (layer *Packet8BLayer) String() string {
+	return fmt.Sprintf("ID_CHAT_GAME: %s", layer.Message)
+}
+
+// TypeString implements RakNetPacket.TypeString()
+func (Packet8BLayer) TypeString() string {
+	return "ID_CHAT_GAME"
+}
+
+// Type implements RakNetPacket.Type()
+func (Packet8BLayer) Type() byte {
+	return 0x8B
+}
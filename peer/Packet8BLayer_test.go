@@ -0,0 +1,10 @@
+package peer
+
+import "testing"
+
+func TestPacket8BRoundTrip(t *testing.T) {
+	reader, writer, _ := chatTestPlayers(0)
+	testChatRoundTrip(t, reader, writer, &Packet8BLayer{
+		Message: "server restarting",
+	}, (*extendedReader).DecodePacket8BLayer)
+}
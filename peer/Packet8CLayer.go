@@ -0,0 +1,58 @@
+package peer
+
+import (
+	"fmt"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+)
+
+// Packet8CLayer represents ID_CHAT_PLAYER
+// It contains a private message from Instance to Recipient.
+type Packet8CLayer struct {
+	Instance  *datamodel.Instance
+	Recipient *datamodel.Instance
+	Message   string
+}
+
+func (thisStream *extendedReader) DecodePacket8CLayer(reader PacketReader, layers *PacketLayers) (RakNetPacket, error) {
+	layer := &Packet8CLayer{}
+	var err error
+
+	layer.Instance, err = thisStream.readChatPlayer(reader)
+	if err != nil {
+		return layer, err
+	}
+	layer.Recipient, err = thisStream.readChatPlayer(reader)
+	if err != nil {
+		return layer, err
+	}
+	layer.Message, err = thisStream.readChatMessage()
+	return layer, err
+}
+
+// Serialize implements RakNetPacket.Serialize
+func (layer *Packet8CLayer) Serialize(writer PacketWriter, stream *extendedWriter) error {
+	err := stream.writeChatPlayer(layer.Instance)
+	if err != nil {
+		return err
+	}
+	err = stream.writeChatPlayer(layer.Recipient)
+	if err != nil {
+		return err
+	}
+	return stream.writeUint32AndString(layer.Message)
+}
+
+func (layer *Packet8CLayer) String() string {
+	return fmt.Sprintf("ID_CHAT_PLAYER: <%s> to <%s>", layer.Instance.GetFullName(), layer.Recipient.GetFullName())
+}
+
+// TypeString implements RakNetPacket.TypeString()
+func (Packet8CLayer) TypeString() string {
+	return "ID_CHAT_PLAYER"
+}
+
+// Type implements RakNetPacket.Type()
+func (Packet8CLayer) Type() byte {
+	return 0x8C
+}
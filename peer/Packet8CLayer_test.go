@@ -0,0 +1,12 @@
+package peer
+
+import "testing"
+
+func TestPacket8CRoundTrip(t *testing.T) {
+	reader, writer, players := chatTestPlayers(2)
+	testChatRoundTrip(t, reader, writer, &Packet8CLayer{
+		Instance:  players[0],
+		Recipient: players[1],
+		Message:   "hello",
+	}, (*extendedReader).DecodePacket8CLayer)
+}
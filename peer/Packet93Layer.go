@@ -52,6 +52,9 @@ func (thisStream *extendedReader) DecodePacket93Layer(reader PacketReader, layer
 		}
 		layer.Params[string(name)] = string(value) == "true"
 	}
+	reader.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.recordFlags(layer)
+	})
 
 	return layer, nil
 }
@@ -59,6 +62,9 @@ func (thisStream *extendedReader) DecodePacket93Layer(reader PacketReader, layer
 // Serialize implements RakNetPacket.Serialize
 func (layer *Packet93Layer) Serialize(writer PacketWriter, stream *extendedWriter) error {
 	var err error
+	writer.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.recordFlags(layer)
+	})
 
 	var flags byte
 	if layer.ProtocolSchemaSync {
@@ -5,6 +5,9 @@ import "fmt"
 // Packet96Layer represents ID_REQUEST_STATS
 type Packet96Layer struct {
 	Request bool
+	// Version is the version of the requested stats. Replies and requests
+	// older than ProtocolVersionVersionedStats don't contain it, so it is
+	// taken from the last request of the connection.
 	Version uint32
 }
 
@@ -16,12 +19,21 @@ func (thisStream *extendedReader) DecodePacket96Layer(reader PacketReader, layer
 	if err != nil {
 		return layer, err
 	}
-	if !layer.Request {
+	profile := reader.Context().Profile()
+	if !layer.Request || !profile.versionedStats() {
+		// Replies and older requests don't contain the version
+		layer.Version = profile.StatsVersion
 		return layer, err
 	}
 
 	layer.Version, err = thisStream.readUint32BE()
-	return layer, err
+	if err != nil {
+		return layer, err
+	}
+	reader.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.StatsVersion = layer.Version
+	})
+	return layer, nil
 }
 
 // Serialize implements RakNetPacket.Serialize
@@ -30,14 +42,21 @@ func (layer *Packet96Layer) Serialize(writer PacketWriter, stream *extendedWrite
 	if err != nil {
 		return err
 	}
-	if !layer.Request {
+	profile := writer.Context().Profile()
+	if !layer.Request || !profile.versionedStats() {
 		return nil
 	}
+	writer.Context().updateProfile(func(profile *ProtocolProfile) {
+		profile.StatsVersion = layer.Version
+	})
 
 	return stream.writeUint32BE(layer.Version)
 }
 
 func (layer *Packet96Layer) String() string {
+	if !layer.Request {
+		return fmt.Sprintf("ID_REQUEST_STATS: Reply, Version %d", layer.Version)
+	}
 	return fmt.Sprintf("ID_REQUEST_STATS: Version %d", layer.Version)
 }
 
@@ -113,12 +113,7 @@ func (thisStream *extendedReader) DecodePacket97Layer(reader PacketReader, layer
 			if err != nil {
 				return layer, err
 			}
-			typeString, ok := TypeNames[thisProperty.Type]
-			if ok {
-				thisProperty.TypeString = typeString
-			} else {
-				thisProperty.TypeString = fmt.Sprintf("Unknown %d", thisProperty.Type)
-			}
+			thisProperty.TypeString = typeString(thisProperty.Type)
 
 			if thisProperty.Type == 7 {
 				thisProperty.EnumID, err = stream.readUint16BE()
@@ -182,7 +177,7 @@ func (thisStream *extendedReader) DecodePacket97Layer(reader PacketReader, layer
 				if err != nil {
 					return layer, err
 				}
-				thisArgument.TypeString = TypeNames[thisArgument.Type]
+				thisArgument.TypeString = typeString(thisArgument.Type)
 				thisArgument.EnumID, err = stream.readUint16BE()
 				if err != nil {
 					return layer, err
@@ -230,6 +225,9 @@ func (thisStream *extendedReader) DecodePacket97Layer(reader PacketReader, layer
 		layer.Schema.OptimizedStrings[i] = optimizedString
 	}
 
+	if reader.Context().APIDump != nil {
+		layer.Schema.LoadEnumItems(reader.Context().APIDump)
+	}
 	reader.Context().NetworkSchema = layer.Schema
 
 	return layer, err
@@ -61,10 +61,11 @@ func (logicHandler *PacketLogicHandler) startDataPing() {
 		for {
 			select {
 			case <-logicHandler.dataPingTicker.C:
+				// Use the same ping format as the remote peer
 				logicHandler.WritePacket(&Packet83Layer{
 					[]Packet83Subpacket{&Packet83_05{
 						Timestamp:     uint64(time.Now().UnixNano() / int64(time.Millisecond)),
-						PacketVersion: 0,
+						PacketVersion: logicHandler.Context.Profile().PingVersion,
 					}},
 				})
 			case <-logicHandler.RunningContext.Done():
@@ -122,18 +123,32 @@ const (
 // to the remote peer. Note that it doesn't close the
 // underlying connection
 func (logicHandler *PacketLogicHandler) Disconnect() {
-	if logicHandler.Connected {
-		logicHandler.WritePacket(&Packet15Layer{
-			Reason: -1,
-		})
-		logicHandler.Connected = false
-		<-logicHandler.GenericEvents.Emit("disconnected", LocalDisconnection, int32(-1))
+	logicHandler.DisconnectWithReason(-1)
+}
 
+// DisconnectWithReason is like Disconnect(), but allows specifying
+// the disconnection reason
+func (logicHandler *PacketLogicHandler) DisconnectWithReason(reason int32) {
+	if logicHandler.notifyDisconnection(reason) {
 		logicHandler.Connection.Close()
 		logicHandler.cleanup()
 	}
 }
 
+// notifyDisconnection sends the disconnection packet and emits the
+// disconnection event. It returns false if the peer was already disconnected.
+func (logicHandler *PacketLogicHandler) notifyDisconnection(reason int32) bool {
+	if !logicHandler.Connected {
+		return false
+	}
+	logicHandler.WritePacket(&Packet15Layer{
+		Reason: reason,
+	})
+	logicHandler.Connected = false
+	<-logicHandler.GenericEvents.Emit("disconnected", LocalDisconnection, reason)
+	return true
+}
+
 func (logicHandler *PacketLogicHandler) sendDataPingBack() {
 	response := &Packet83_06{
 		Timestamp:  uint64(time.Now().UnixNano() / int64(time.Millisecond)),
@@ -236,6 +251,14 @@ func (logicHandler *PacketLogicHandler) SendEvent(instance *datamodel.Instance,
 	)
 }
 
+// SendChat sends a chat message on behalf of the player using ID_CHAT_ALL
+func (logicHandler *PacketLogicHandler) SendChat(player *datamodel.Instance, message string) error {
+	return logicHandler.WritePacket(&Packet87Layer{
+		Instance: player,
+		Message:  message,
+	})
+}
+
 // SendHackFlag attempts to fire the StatsAvailable event on the given player
 func (logicHandler *PacketLogicHandler) SendHackFlag(player *datamodel.Instance, flag string) error {
 	return logicHandler.SendEvent(player, "StatsAvailable", rbxfile.ValueString(flag))
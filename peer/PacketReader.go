@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/Gskartwii/roblox-dissector/datamodel"
 	"github.com/robloxapi/rbxfile"
@@ -13,9 +14,7 @@ import (
 	"github.com/olebedev/emitter"
 )
 
-type decoderFunc func(*extendedReader, PacketReader, *PacketLayers) (RakNetPacket, error)
-
-var packetDecoders = map[byte]decoderFunc{
+var packetDecoders = map[byte]PacketDecoder{
 	0x7B: (*extendedReader).DecodePacket05Layer,
 	0x7E: (*extendedReader).DecodePacket06Layer,
 	0x78: (*extendedReader).DecodePacket07Layer,
@@ -34,7 +33,10 @@ var packetDecoders = map[byte]decoderFunc{
 	0x85: (*extendedReader).DecodePacket85Layer,
 	0x86: (*extendedReader).DecodePacket86Layer,
 	0x87: (*extendedReader).DecodePacket87Layer,
+	0x88: (*extendedReader).DecodePacket88Layer,
 	0x8A: (*extendedReader).DecodePacket8ALayer,
+	0x8B: (*extendedReader).DecodePacket8BLayer,
+	0x8C: (*extendedReader).DecodePacket8CLayer,
 	0x8D: (*extendedReader).DecodePacket8DLayer,
 	0x8F: (*extendedReader).DecodePacket8FLayer,
 	0x90: (*extendedReader).DecodePacket90Layer,
@@ -67,7 +69,7 @@ type PacketReader interface {
 type contextualHandler struct {
 	context *CommunicationContext
 	caches  *Caches
-	// sharedStrings contains a map of deferred strings indexed by their MD5 hash
+	// sharedStrings contains a map of deferred strings indexed by their hash
 	sharedStrings map[string]rbxfile.ValueSharedString
 }
 
@@ -171,21 +173,20 @@ func (reader *DefaultPacketReader) readOffline(stream *extendedReader, packetTyp
 	layers.Root.Logger = log.New(layers.Root.logBuffer, "", log.Lmicroseconds|log.Ltime)
 	layers.UniqueID = reader.context.uniqueID
 	reader.context.uniqueID++
-	decoder := packetDecoders[packetType]
-	if decoder != nil {
-		layers.Main, err = decoder(stream, reader, layers)
-		if err != nil {
-			layers.Error = fmt.Errorf("failed to decode offline packet %02X: %s", packetType, err.Error())
-		}
-	} else {
-		layers.Error = fmt.Errorf("unknown offline packet %02X", packetType)
+	decoder := packetDecoder(packetType)
+	if decoder == nil {
+		decoder = (*extendedReader).DecodeOpaquePacket
+	}
+	layers.Main, err = decoder(stream, reader, layers)
+	if err != nil {
+		layers.Error = fmt.Errorf("failed to decode offline packet %02X: %s", packetType, err.Error())
 	}
 }
 
 func (reader *DefaultPacketReader) readGeneric(stream *extendedReader, layers *PacketLayers) {
 	var err error
 	if layers.PacketType == 0x1B { // ID_TIMESTAMP
-		tsLayer, err := packetDecoders[0x1B](stream, reader, layers)
+		tsLayer, err := packetDecoder(0x1B)(stream, reader, layers)
 		if err != nil {
 			layers.Reliability.SplitBuffer.Logger.Println("error:", err.Error())
 			layers.Error = fmt.Errorf("failed to decode timestamped packet: %s", err.Error())
@@ -202,18 +203,17 @@ func (reader *DefaultPacketReader) readGeneric(stream *extendedReader, layers *P
 		layers.Reliability.SplitBuffer.HasPacketType = true
 		layers.PacketType = packetType
 	}
-	decoder := packetDecoders[layers.PacketType]
+	decoder := packetDecoder(layers.PacketType)
+	if decoder == nil {
+		// Unknown packets are kept as-is so that they can still be forwarded
+		decoder = (*extendedReader).DecodeOpaquePacket
+	}
+	layers.Main, err = decoder(stream, reader, layers)
 	// TODO: Should we really void partial deserializations?
-	if decoder != nil {
-		layers.Main, err = decoder(stream, reader, layers)
-
-		if err != nil {
-			layers.Main = nil
-			layers.Reliability.SplitBuffer.Logger.Println("error:", err.Error())
-			layers.Error = fmt.Errorf("failed to decode reliable packet %02X: %s", layers.PacketType, err.Error())
-		}
-	} else {
-		layers.Error = fmt.Errorf("unknown packetType %d", layers.PacketType)
+	if err != nil {
+		layers.Main = nil
+		layers.Reliability.SplitBuffer.Logger.Println("error:", err.Error())
+		layers.Error = fmt.Errorf("failed to decode reliable packet %02X: %s", layers.PacketType, err.Error())
 	}
 }
 
@@ -353,64 +353,121 @@ func (reader *DefaultPacketReader) HandlePacket01(e *emitter.Event) {
 	if e.Args[1].(*PacketLayers).Root.FromClient {
 		return
 	}
-	err := packet.Instance.SetParent(nil)
-	if err != nil {
-		e.Args[1].(*PacketLayers).Root.Logger.Println("delete error:", err.Error())
-	}
+	reader.context.Journal.recordDestroy(e.Args[1].(*PacketLayers).UniqueID, packet.Instance)
 	reader.context.removeInstance(packet.Instance)
 }
 
-func (reader *DefaultPacketReader) handleReplicationInstance(inst *ReplicationInstance) error {
+func (reader *DefaultPacketReader) handleReplicationInstance(inst *ReplicationInstance, layers *PacketLayers) error {
+	uniqueID := layers.UniqueID
+	journal := reader.context.Journal
+	history := reader.context.DataModel.History
+	unresolved := reader.context.UnresolvedReferences
+	timestamp := time.Now()
+	oldParent := inst.Instance.Parent()
 	// First, assign the properties
 	inst.Instance.PropertiesMutex.Lock()
 	for name, val := range inst.Properties {
+		// The properties of an instance that was already in the DataModel
+		// must be restored if the change is undone
+		if oldParent != nil {
+			journal.recordProperty(uniqueID, inst.Instance, name, inst.Instance.Properties[name], val)
+		}
+		history.Record(inst.Instance, name, datamodel.PropertyRecord{
+			UniqueID:   uniqueID,
+			Timestamp:  timestamp,
+			Version:    -1,
+			Value:      val,
+			FromClient: layers.Root.FromClient,
+		})
 		// Do not call Set() here. Nothing should be listening to PropertyEmitter
 		// and we have the lock
 		inst.Instance.Properties[name] = val
 	}
 	inst.Instance.PropertiesMutex.Unlock()
+	for name, val := range inst.Properties {
+		unresolved.Add(inst.Instance, name, val)
+	}
 
 	// Once they are assigned, we can release this instance to be used by the DataModel
-	return inst.Instance.SetParent(inst.Parent)
+	journal.recordParent(uniqueID, JournalNewInstance, inst.Instance, oldParent, inst.Parent)
+	err := inst.Instance.SetParent(inst.Parent)
+	if err != nil {
+		return err
+	}
+	// Patch the properties of instances that were replicated before this one
+	unresolved.Resolve(inst.Instance)
+	return nil
 }
 
 // HandlePacket02 is the default handler for ID_REPLIC_NEW_INSTANCE packets
 func (reader *DefaultPacketReader) HandlePacket02(e *emitter.Event) {
 	packet := e.Args[0].(*Packet83_02)
 
-	err := reader.handleReplicationInstance(packet.ReplicationInstance)
+	layers := e.Args[1].(*PacketLayers)
+	err := reader.handleReplicationInstance(packet.ReplicationInstance, layers)
 	if err != nil {
-		e.Args[1].(*PacketLayers).Error = err
+		layers.Error = err
 	}
 }
 
 // HandlePacket03 is the default handler for ID_REPLIC_PROP packets
 func (reader *DefaultPacketReader) HandlePacket03(e *emitter.Event) {
 	packet := e.Args[0].(*Packet83_03)
+	layers := e.Args[1].(*PacketLayers)
 	if packet.Schema == nil {
 		// Parent handler
-		err := packet.Value.(datamodel.ValueReference).Instance.AddChild(packet.Instance)
+		newParent := packet.Value.(datamodel.ValueReference).Instance
+		reader.context.Journal.recordParent(layers.UniqueID, JournalParent, packet.Instance, packet.Instance.Parent(), newParent)
+		err := newParent.AddChild(packet.Instance)
 		if err != nil {
-			e.Args[1].(*PacketLayers).Error = err
+			layers.Error = err
 		}
 		return
 	}
+	reader.context.Journal.recordProperty(layers.UniqueID, packet.Instance, packet.Schema.Name, packet.Instance.Get(packet.Schema.Name), packet.Value)
+	version := int32(-1)
+	if packet.HasVersion {
+		version = packet.Version
+	}
+	reader.context.DataModel.History.Record(packet.Instance, packet.Schema.Name, datamodel.PropertyRecord{
+		UniqueID:   layers.UniqueID,
+		Timestamp:  time.Now(),
+		Version:    version,
+		Value:      packet.Value,
+		FromClient: layers.Root.FromClient,
+	})
 	packet.Instance.Set(packet.Schema.Name, packet.Value)
+	reader.context.UnresolvedReferences.Add(packet.Instance, packet.Schema.Name, packet.Value)
 }
 
 // HandlePacket07 is the default handler fo ID_REPLIC_EVENT packets
 func (reader *DefaultPacketReader) HandlePacket07(e *emitter.Event) {
 	packet := e.Args[0].(*Packet83_07)
+	reader.context.UnresolvedReferences.AddEventArguments(packet.Instance, packet.Schema.Name, packet.Event.Arguments)
 	packet.Instance.FireEvent(packet.Schema.Name, packet.Event.Arguments...)
 }
 
 // HandlePacket0B is the default handler for ID_REPLIC_JOIN_DATA packets
 func (reader *DefaultPacketReader) HandlePacket0B(e *emitter.Event) {
 	packet := e.Args[0].(*Packet83_0B)
+	layers := e.Args[1].(*PacketLayers)
+	for _, inst := range packet.Instances {
+		err := reader.handleReplicationInstance(inst, layers)
+		if err != nil {
+			layers.Error = err
+			return
+		}
+	}
+}
+
+// HandlePacket0D is the default handler for ID_REPLIC_STREAM_DATA packets
+func (reader *DefaultPacketReader) HandlePacket0D(e *emitter.Event) {
+	packet := e.Args[0].(*Packet83_0D)
+	layers := e.Args[1].(*PacketLayers)
 	for _, inst := range packet.Instances {
-		err := reader.handleReplicationInstance(inst)
+		err := reader.handleReplicationInstance(inst, layers)
 		if err != nil {
-			e.Args[1].(*PacketLayers).Error = err
+			layers.Error = err
 			return
 		}
 	}
@@ -419,6 +476,7 @@ func (reader *DefaultPacketReader) HandlePacket0B(e *emitter.Event) {
 // HandlePacket13 is the default handler for ID_REPLIC_ATOMIC packets
 func (reader *DefaultPacketReader) HandlePacket13(e *emitter.Event) {
 	packet := e.Args[0].(*Packet83_13)
+	reader.context.Journal.recordParent(e.Args[1].(*PacketLayers).UniqueID, JournalParent, packet.Instance, packet.Instance.Parent(), packet.Parent)
 	packet.Instance.SetParent(packet.Parent)
 }
 
@@ -431,7 +489,8 @@ func (reader *DefaultPacketReader) HandlePacket81(e *emitter.Event) {
 }
 
 // BindDataModelHandlers binds the default handlers so that the PacketReader
-// will update the DataModel based on what it reads
+// will update the DataModel based on what it reads. Instances streamed in
+// using ID_REPLIC_STREAM_DATA are added to the DataModel like join data.
 func (reader *DefaultPacketReader) BindDataModelHandlers() {
 	reader.PacketEmitter.On("ID_SET_GLOBALS", reader.HandlePacket81, emitter.Void)
 	reader.DataEmitter.On("ID_REPLIC_DELETE_INSTANCE", reader.HandlePacket01, emitter.Void)
@@ -439,6 +498,7 @@ func (reader *DefaultPacketReader) BindDataModelHandlers() {
 	reader.DataEmitter.On("ID_REPLIC_PROP", reader.HandlePacket03, emitter.Void)
 	reader.DataEmitter.On("ID_REPLIC_EVENT", reader.HandlePacket07, emitter.Void)
 	reader.DataEmitter.On("ID_REPLIC_JOIN_DATA", reader.HandlePacket0B, emitter.Void)
+	reader.DataEmitter.On("ID_REPLIC_STREAM_DATA", reader.HandlePacket0D, emitter.Void)
 	reader.DataEmitter.On("ID_REPLIC_ATOMIC", reader.HandlePacket13, emitter.Void)
 }
 
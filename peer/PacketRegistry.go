@@ -0,0 +1,174 @@
+package peer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// PacketDecoder decodes a top-level packet. The stream is positioned
+// right after the packet ID.
+type PacketDecoder func(*ExtendedReader, PacketReader, *PacketLayers) (RakNetPacket, error)
+
+// Packet83Decoder decodes an ID_DATA subpacket. The stream is positioned
+// right after the subpacket ID.
+type Packet83Decoder func(*ExtendedReader, PacketReader, *PacketLayers) (Packet83Subpacket, error)
+
+// decodersMutex guards packetDecoders, packet83Decoders,
+// PacketNames and Packet83Subpackets
+var decodersMutex sync.RWMutex
+
+// RegisterPacketDecoder registers a decoder for a top-level packet ID.
+// Decoded packets are serialized using their Serialize() method.
+// If name is not empty, it will be registered in PacketNames.
+// An error is returned if the ID already has a decoder, so built-in
+// packets can't be replaced. Decoders should be registered before
+// any packets are read.
+func RegisterPacketDecoder(packetType byte, name string, decoder PacketDecoder) error {
+	decodersMutex.Lock()
+	defer decodersMutex.Unlock()
+	if _, ok := packetDecoders[packetType]; ok {
+		return fmt.Errorf("packet %02X already has a decoder", packetType)
+	}
+	packetDecoders[packetType] = decoder
+	if name != "" {
+		PacketNames[packetType] = name
+	}
+	return nil
+}
+
+// RegisterPacket83Decoder registers a decoder for an ID_DATA subpacket ID.
+// If name is not empty, it will be registered in Packet83Subpackets.
+// An error is returned if the ID already has a decoder, so built-in
+// subpackets can't be replaced. Decoders should be registered before
+// any packets are read.
+func RegisterPacket83Decoder(subpacketType uint8, name string, decoder Packet83Decoder) error {
+	decodersMutex.Lock()
+	defer decodersMutex.Unlock()
+	if _, ok := packet83Decoders[subpacketType]; ok {
+		return fmt.Errorf("subpacket %02X already has a decoder", subpacketType)
+	}
+	packet83Decoders[subpacketType] = decoder
+	if name != "" {
+		Packet83Subpackets[subpacketType] = name
+	}
+	return nil
+}
+
+// PacketName returns the registered name of a top-level packet ID.
+// It is safe to call while decoders are being registered.
+func PacketName(packetType byte) (string, bool) {
+	decodersMutex.RLock()
+	defer decodersMutex.RUnlock()
+	name, ok := PacketNames[packetType]
+	return name, ok
+}
+
+// Packet83SubpacketName returns the registered name of an ID_DATA subpacket ID.
+// It is safe to call while decoders are being registered.
+func Packet83SubpacketName(subpacketType uint8) (string, bool) {
+	decodersMutex.RLock()
+	defer decodersMutex.RUnlock()
+	name, ok := Packet83Subpackets[subpacketType]
+	return name, ok
+}
+
+func packetDecoder(packetType byte) PacketDecoder {
+	decodersMutex.RLock()
+	defer decodersMutex.RUnlock()
+	return packetDecoders[packetType]
+}
+
+func packet83Decoder(subpacketType uint8) (Packet83Decoder, bool) {
+	decodersMutex.RLock()
+	defer decodersMutex.RUnlock()
+	decoder, ok := packet83Decoders[subpacketType]
+	return decoder, ok
+}
+
+// OpaquePacket represents a packet that has no registered decoder
+// It contains the raw payload so that it can be forwarded losslessly.
+type OpaquePacket struct {
+	PacketType byte
+	// Payload doesn't include the packet ID
+	Payload []byte
+}
+
+// DecodeOpaquePacket reads the rest of the packet into an OpaquePacket
+func (thisStream *extendedReader) DecodeOpaquePacket(reader PacketReader, layers *PacketLayers) (RakNetPacket, error) {
+	var err error
+	layer := &OpaquePacket{PacketType: layers.PacketType}
+	layer.Payload, err = ioutil.ReadAll(thisStream)
+	return layer, err
+}
+
+// Serialize implements RakNetPacket.Serialize()
+func (layer *OpaquePacket) Serialize(writer PacketWriter, stream *extendedWriter) error {
+	return stream.allBytes(layer.Payload)
+}
+
+func (layer *OpaquePacket) String() string {
+	return fmt.Sprintf("%s: %d bytes", layer.TypeString(), len(layer.Payload))
+}
+
+// TypeString implements RakNetPacket.TypeString()
+func (layer *OpaquePacket) TypeString() string {
+	name, ok := PacketName(layer.PacketType)
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("ID_UNKNOWN_%02X", layer.PacketType)
+}
+
+// Type implements RakNetPacket.Type()
+func (layer *OpaquePacket) Type() byte {
+	return layer.PacketType
+}
+
+// OpaqueSubpacket represents an ID_DATA subpacket that has no registered decoder
+// Because the length of the subpacket is unknown, it contains the rest of
+// the ID_DATA packet, including any subpackets that follow it.
+type OpaqueSubpacket struct {
+	SubpacketType uint8
+	// Payload doesn't include the subpacket ID or the
+	// terminator of the ID_DATA packet
+	Payload []byte
+}
+
+func (thisStream *extendedReader) decodeOpaqueSubpacket(subpacketType uint8) (*OpaqueSubpacket, error) {
+	inner := &OpaqueSubpacket{SubpacketType: subpacketType}
+	payload, err := ioutil.ReadAll(thisStream)
+	if err != nil {
+		return inner, err
+	}
+	if len(payload) == 0 || payload[len(payload)-1] != 0 {
+		return inner, errors.New("missing ID_DATA terminator")
+	}
+	// Packet83Layer.Serialize() will write the terminator
+	inner.Payload = payload[:len(payload)-1]
+	return inner, nil
+}
+
+// Serialize implements Packet83Subpacket.Serialize()
+func (layer *OpaqueSubpacket) Serialize(writer PacketWriter, stream *extendedWriter) error {
+	return stream.allBytes(layer.Payload)
+}
+
+// Type implements Packet83Subpacket.Type()
+func (layer *OpaqueSubpacket) Type() uint8 {
+	return layer.SubpacketType
+}
+
+// TypeString implements Packet83Subpacket.TypeString()
+func (layer *OpaqueSubpacket) TypeString() string {
+	name, ok := Packet83SubpacketName(layer.SubpacketType)
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("ID_REPLIC_UNKNOWN_%02X", layer.SubpacketType)
+}
+
+func (layer *OpaqueSubpacket) String() string {
+	return fmt.Sprintf("%s: %d bytes", layer.TypeString(), len(layer.Payload))
+}
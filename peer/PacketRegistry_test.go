@@ -0,0 +1,80 @@
+package peer
+
+import (
+	"bytes"
+	"testing"
+)
+
+// customSubpacket is a Packet83Subpacket registered by TestRegisterPacket83Decoder
+type customSubpacket struct {
+	Value uint8
+}
+
+func (layer *customSubpacket) Serialize(writer PacketWriter, stream *extendedWriter) error {
+	return stream.WriteByte(layer.Value)
+}
+
+func (customSubpacket) Type() uint8 {
+	return 0x20
+}
+
+func (customSubpacket) TypeString() string {
+	return "ID_REPLIC_CUSTOM"
+}
+
+func (layer *customSubpacket) String() string {
+	return "ID_REPLIC_CUSTOM"
+}
+
+func TestRegisterPacket83Decoder(t *testing.T) {
+	err := RegisterPacket83Decoder(0x20, "ID_REPLIC_CUSTOM", func(stream *ExtendedReader, reader PacketReader, layers *PacketLayers) (Packet83Subpacket, error) {
+		value, err := stream.ReadUint8()
+		return &customSubpacket{Value: value}, err
+	})
+	if err != nil {
+		t.Fatalf("failed to register decoder: %s", err.Error())
+	}
+	defer func() {
+		decodersMutex.Lock()
+		delete(packet83Decoders, 0x20)
+		delete(Packet83Subpackets, 0x20)
+		decodersMutex.Unlock()
+	}()
+
+	if err := RegisterPacket83Decoder(0x20, "", nil); err == nil {
+		t.Error("decoder was registered twice")
+	}
+	if err := RegisterPacket83Decoder(0x03, "", nil); err == nil {
+		t.Error("built-in decoder was replaced")
+	}
+	if err := RegisterPacketDecoder(0x83, "", nil); err == nil {
+		t.Error("built-in packet decoder was replaced")
+	}
+
+	// A custom subpacket followed by an unknown one and the terminator
+	payload := []byte{0x20, 0xAB, 0x21, 0x01, 0x02, 0x00}
+	stream := &extendedReader{bytes.NewReader(payload)}
+	packet, err := stream.DecodePacket83Layer(nil, &PacketLayers{})
+	if err != nil {
+		t.Fatalf("failed to decode packet: %s", err.Error())
+	}
+	subpackets := packet.(*Packet83Layer).SubPackets
+	if len(subpackets) != 2 {
+		t.Fatalf("expected 2 subpackets, got %d", len(subpackets))
+	}
+	if custom, ok := subpackets[0].(*customSubpacket); !ok || custom.Value != 0xAB {
+		t.Errorf("custom subpacket wasn't decoded: %v", subpackets[0])
+	}
+	if opaque, ok := subpackets[1].(*OpaqueSubpacket); !ok || !bytes.Equal(opaque.Payload, []byte{0x01, 0x02}) {
+		t.Errorf("unknown subpacket wasn't kept: %v", subpackets[1])
+	}
+
+	var output bytes.Buffer
+	err = packet.Serialize(nil, &extendedWriter{&output})
+	if err != nil {
+		t.Fatalf("failed to serialize packet: %s", err.Error())
+	}
+	if !bytes.Equal(output.Bytes(), payload) {
+		t.Errorf("serialized packet %X differs from %X", output.Bytes(), payload)
+	}
+}
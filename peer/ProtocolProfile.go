@@ -0,0 +1,120 @@
+package peer
+
+import "fmt"
+
+// DefaultCapabilities are the capabilities assumed for connections
+// whose handshake wasn't observed. They are also the capabilities
+// offered by CustomServer.
+const DefaultCapabilities = CapabilityServerCopiesPlayerGui3 | CapabilityIHasMinDistToUnstreamed | CapabilityReplicateLuau | CapabilityPositionBasedStreaming | CapabilityVersionedIDSync | CapabilitySystemAddressIsPeerId | CapabilityStreamingPrefetch | CapabilityUseBlake2BHashInSharedString | 0xDC000
+
+// ProtocolProfile describes the protocol version and features
+// negotiated in a connection. It is populated as the handshake packets
+// are decoded or serialized, and consulted by packets whose format
+// depends on the protocol version. Use CommunicationContext.Profile()
+// to get a copy of the profile of a connection.
+type ProtocolProfile struct {
+	// RakNetVersion is the RakNet protocol version from ID_OPEN_CONNECTION_REQUEST_1
+	RakNetVersion uint8
+	// SupportedVersion is the protocol version from ID_OPEN_CONNECTION_REQUEST_2/REPLY_2
+	SupportedVersion uint32
+	// RequestedCapabilities are the capabilities requested by the client
+	// in ID_OPEN_CONNECTION_REQUEST_2
+	RequestedCapabilities uint64
+	// Capabilities are the capabilities negotiated in ID_OPEN_CONNECTION_REPLY_2
+	Capabilities uint64
+	// HasCapabilities indicates whether Capabilities has been negotiated
+	HasCapabilities bool
+	// Flags are the parameters set by the server in ID_DICTIONARY_FORMAT.
+	// The map is shared by the copies of the profile and must not be modified.
+	Flags map[string]bool
+	// PingVersion is the ID_REPLIC_PING format version used by the remote peer
+	PingVersion uint8
+	// StatsVersion is the ID_REQUEST_STATS version requested by the server
+	StatsVersion uint32
+	// StreamJob indicates whether streaming was enabled in ID_SET_GLOBALS
+	StreamJob bool
+}
+
+// HasCapability reports whether all of the given capabilities are in use
+// in the connection. If the capabilities haven't been negotiated, for example
+// because the capture doesn't contain the handshake, DefaultCapabilities
+// are assumed.
+func (profile *ProtocolProfile) HasCapability(capability uint64) bool {
+	if !profile.HasCapabilities {
+		return capability&DefaultCapabilities == capability
+	}
+	return profile.Capabilities&capability == capability
+}
+
+// The following protocol versions changed the format of packets. They are
+// compared with the version negotiated in ID_OPEN_CONNECTION_REQUEST_2/REPLY_2.
+const (
+	// ProtocolVersionVersionedPing is the first protocol version whose
+	// ID_REPLIC_PING begins with a format version. Older peers send
+	// IsPingBack in its place and only use the 64-bit timestamp format.
+	ProtocolVersionVersionedPing = 33
+	// ProtocolVersionInvertedPingStats is the first protocol version whose
+	// ping hack flags are inverted when bit 5 of the timestamp is set
+	ProtocolVersionInvertedPingStats = 34
+	// ProtocolVersionVersionedStats is the first protocol version whose
+	// ID_REQUEST_STATS requests carry the version of the requested stats
+	ProtocolVersionVersionedStats = 35
+	// CurrentProtocolVersion is assumed for connections whose handshake
+	// wasn't observed. It is also the version offered by CustomServer.
+	CurrentProtocolVersion = 36
+)
+
+// ProtocolVersion returns the negotiated protocol version, or
+// CurrentProtocolVersion if it is unknown
+func (profile *ProtocolProfile) ProtocolVersion() uint32 {
+	if profile.SupportedVersion == 0 {
+		return CurrentProtocolVersion
+	}
+	return profile.SupportedVersion
+}
+
+// versionedPing reports whether ID_REPLIC_PING begins with a format version
+func (profile *ProtocolProfile) versionedPing() bool {
+	return profile.ProtocolVersion() >= ProtocolVersionVersionedPing
+}
+
+// extraStatsMask returns the mask that the hack flags in ID_REPLIC_PING
+// and ID_REPLIC_PING_BACK are XORed with. Since
+// ProtocolVersionInvertedPingStats, the flags are inverted when bit 5
+// of the timestamp is set.
+func (profile *ProtocolProfile) extraStatsMask(timestamp uint64) uint32 {
+	if profile.ProtocolVersion() < ProtocolVersionInvertedPingStats || timestamp&0x20 == 0 {
+		return 0
+	}
+	return 0xFFFFFFFF
+}
+
+// versionedStats reports whether ID_REQUEST_STATS requests carry a version
+func (profile *ProtocolProfile) versionedStats() bool {
+	return profile.ProtocolVersion() >= ProtocolVersionVersionedStats
+}
+
+func (profile *ProtocolProfile) recordOpenConnectionRequest(layer *Packet07Layer) {
+	profile.SupportedVersion = layer.SupportedVersion
+	profile.RequestedCapabilities = layer.Capabilities
+}
+
+func (profile *ProtocolProfile) recordOpenConnectionReply(layer *Packet08Layer) {
+	profile.SupportedVersion = layer.SupportedVersion
+	profile.Capabilities = layer.Capabilities
+	profile.HasCapabilities = true
+}
+
+func (profile *ProtocolProfile) recordFlags(layer *Packet93Layer) {
+	profile.Flags = make(map[string]bool, len(layer.Params))
+	for name, value := range layer.Params {
+		profile.Flags[name] = value
+	}
+}
+
+func (profile *ProtocolProfile) String() string {
+	if !profile.HasCapabilities {
+		return fmt.Sprintf("RakNet version %d, capabilities not negotiated", profile.RakNetVersion)
+	}
+	return fmt.Sprintf("RakNet version %d, protocol version %d, capabilities %X, %d flags", profile.RakNetVersion, profile.SupportedVersion, profile.Capabilities, len(profile.Flags))
+}
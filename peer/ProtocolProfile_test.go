@@ -0,0 +1,190 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/robloxapi/rbxfile"
+)
+
+// oldCapabilities are the capabilities of a peer that predates
+// peer ID system addresses, Luau replication and BLAKE2b hashes
+const oldCapabilities = CapabilityBasic | CapabilityServerCopiesPlayerGui3
+
+// profileTestHandlers returns a reader and a writer whose connection
+// negotiated the given protocol version and capabilities
+func profileTestHandlers(version uint32, capabilities uint64) (*DefaultPacketReader, *DefaultPacketWriter) {
+	context := NewCommunicationContext()
+	context.updateProfile(func(profile *ProtocolProfile) {
+		profile.SupportedVersion = version
+		profile.Capabilities = capabilities
+		profile.HasCapabilities = true
+	})
+	reader := NewPacketReader()
+	reader.SetContext(context)
+	writer := NewPacketWriter()
+	writer.SetContext(context)
+	return reader, writer
+}
+
+func TestPingProfiles(t *testing.T) {
+	// IsPingBack or version 0, a timestamp with bit 5 set, SendStats and ExtraStats
+	payload := []byte{
+		0x00,
+		0, 0, 0, 0, 0, 0, 0, 0x20,
+		0, 0, 0, 1,
+		0, 0, 0, 1,
+	}
+	for _, test := range []struct {
+		version    uint32
+		extraStats uint32
+	}{
+		{ProtocolVersionVersionedPing - 1, 1},
+		{CurrentProtocolVersion, 0xFFFFFFFE},
+	} {
+		reader, writer := profileTestHandlers(test.version, DefaultCapabilities)
+		stream := &extendedReader{bytes.NewReader(payload)}
+		packet, err := stream.DecodePacket83_05(reader, &PacketLayers{})
+		if err != nil {
+			t.Fatalf("protocol %d: failed to decode ping: %s", test.version, err.Error())
+		}
+		ping := packet.(*Packet83_05)
+		if ping.Timestamp != 0x20 || ping.ExtraStats != test.extraStats {
+			t.Errorf("protocol %d: unexpected ping %+v", test.version, ping)
+		}
+
+		var output bytes.Buffer
+		err = ping.Serialize(writer, &extendedWriter{&output})
+		if err != nil {
+			t.Fatalf("protocol %d: failed to serialize ping: %s", test.version, err.Error())
+		}
+		if !bytes.Equal(output.Bytes(), payload) {
+			t.Errorf("protocol %d: serialized ping %X differs from %X", test.version, output.Bytes(), payload)
+		}
+	}
+
+	_, writer := profileTestHandlers(ProtocolVersionVersionedPing-1, DefaultCapabilities)
+	err := (&Packet83_05{PacketVersion: 2}).Serialize(writer, &extendedWriter{&bytes.Buffer{}})
+	if err == nil {
+		t.Error("versioned ping was written to an old peer")
+	}
+}
+
+func TestStatsRequestProfiles(t *testing.T) {
+	payload := []byte{1, 0, 0, 0, 5}
+
+	reader, _ := profileTestHandlers(ProtocolVersionVersionedStats-1, DefaultCapabilities)
+	source := bytes.NewReader(payload)
+	stream := &extendedReader{source}
+	packet, err := stream.DecodePacket96Layer(reader, &PacketLayers{})
+	if err != nil {
+		t.Fatalf("failed to decode old request: %s", err.Error())
+	}
+	if packet.(*Packet96Layer).Version != 0 || source.Len() != 4 {
+		t.Errorf("old request was read with a version: %+v", packet)
+	}
+
+	reader, _ = profileTestHandlers(CurrentProtocolVersion, DefaultCapabilities)
+	stream = &extendedReader{bytes.NewReader(payload)}
+	packet, err = stream.DecodePacket96Layer(reader, &PacketLayers{})
+	if err != nil {
+		t.Fatalf("failed to decode request: %s", err.Error())
+	}
+	if packet.(*Packet96Layer).Version != 5 {
+		t.Errorf("request version wasn't read: %+v", packet)
+	}
+}
+
+func TestSystemAddressProfiles(t *testing.T) {
+	for _, test := range []struct {
+		capabilities uint64
+		payload      []byte
+		value        datamodel.ValueSystemAddress
+	}{
+		{oldCapabilities, []byte{127, 0, 0, 1, 0xC3, 0x50}, 0x7F000001<<16 | 50000},
+		{DefaultCapabilities, []byte{0x96, 0x01}, 150},
+	} {
+		reader, writer := profileTestHandlers(CurrentProtocolVersion, test.capabilities)
+		stream := &extendedReader{bytes.NewReader(test.payload)}
+		value, err := stream.readSerializedValueGeneric(reader, PropertyTypeSystemAddress, 0, newDeferredStrings(reader))
+		if err != nil {
+			t.Fatalf("capabilities %X: failed to read address: %s", test.capabilities, err.Error())
+		}
+		if value != test.value {
+			t.Errorf("capabilities %X: expected %d, got %v", test.capabilities, test.value, value)
+		}
+
+		var output bytes.Buffer
+		err = (&extendedWriter{&output}).writeSerializedValueGeneric(value, writer, PropertyTypeSystemAddress, newWriteDeferredStrings(writer))
+		if err != nil {
+			t.Fatalf("capabilities %X: failed to write address: %s", test.capabilities, err.Error())
+		}
+		if !bytes.Equal(output.Bytes(), test.payload) {
+			t.Errorf("capabilities %X: written address %X differs from %X", test.capabilities, output.Bytes(), test.payload)
+		}
+	}
+}
+
+func TestBlake2b(t *testing.T) {
+	sum := blake2b128([]byte("abc"))
+	if hex.EncodeToString(sum[:]) != "cf4ab791c62b8d2b2109c90275287816" {
+		t.Errorf("unexpected digest %X", sum)
+	}
+}
+
+func TestScriptProfiles(t *testing.T) {
+	bytecode := rbxfile.ValueSharedString("\x00bytecode")
+	md5Hash := md5.Sum(bytecode)
+	blake2bHash := blake2b128(bytecode)
+	script := datamodel.ValueSignedProtectedString{
+		Signature: []byte{1, 2, 3},
+		// Scripts read from files don't have a hash
+		Value: &datamodel.ValueDeferredString{Value: bytecode},
+	}
+
+	for _, test := range []struct {
+		capabilities uint64
+		hash         string
+		inline       bool
+	}{
+		{oldCapabilities, string(md5Hash[:]), true},
+		{oldCapabilities | CapabilityReplicateLuau, string(md5Hash[:]), false},
+		{DefaultCapabilities, string(blake2bHash[:]), false},
+	} {
+		reader, writer := profileTestHandlers(CurrentProtocolVersion, test.capabilities)
+		var output bytes.Buffer
+		stream := &extendedWriter{&output}
+		writeDeferred := newWriteDeferredStrings(writer)
+		err := stream.writeLuauProtectedString(script, writeDeferred)
+		if err != nil {
+			t.Fatalf("capabilities %X: failed to write script: %s", test.capabilities, err.Error())
+		}
+		if !test.inline && !bytes.HasPrefix(output.Bytes(), []byte(test.hash)) {
+			t.Errorf("capabilities %X: script wasn't written with its hash: %X", test.capabilities, output.Bytes())
+		}
+		err = stream.resolveDeferredStrings(writeDeferred)
+		if err != nil {
+			t.Fatalf("capabilities %X: failed to write shared strings: %s", test.capabilities, err.Error())
+		}
+
+		readStream := &extendedReader{bytes.NewReader(output.Bytes())}
+		deferred := newDeferredStrings(reader)
+		value, err := readStream.readLuauProtectedString(deferred)
+		if err != nil {
+			t.Fatalf("capabilities %X: failed to read script: %s", test.capabilities, err.Error())
+		}
+		err = readStream.resolveDeferredStrings(deferred)
+		if err != nil {
+			t.Fatalf("capabilities %X: failed to read shared strings: %s", test.capabilities, err.Error())
+		}
+		if value.Value.Hash != test.hash || !bytes.Equal(value.Value.Value, bytecode) || !bytes.Equal(value.Signature, script.Signature) {
+			t.Errorf("capabilities %X: script wasn't kept: %X %v", test.capabilities, value.Value.Hash, value)
+		}
+		if test.inline != (len(output.Bytes()) == 1+len(bytecode)+1+len(script.Signature)) {
+			t.Errorf("capabilities %X: unexpected script encoding %X", test.capabilities, output.Bytes())
+		}
+	}
+}
@@ -70,7 +70,8 @@ type PacketLayers struct {
 	UniqueID uint64
 }
 
-// PacketNames contains the names of most packet types
+// PacketNames contains the names of most packet types.
+// Use PacketName() to read it while decoders may be registered.
 var PacketNames = map[byte]string{
 	0x00: "ID_CONNECTED_PING",
 	0x01: "ID_UNCONNECTED_PING",
@@ -127,7 +128,7 @@ func (layers *PacketLayers) String() string {
 			}
 		}
 
-		packetName, ok := PacketNames[layers.PacketType]
+		packetName, ok := PacketName(layers.PacketType)
 		if ok {
 			return packetName
 		}
@@ -0,0 +1,228 @@
+package peer
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/robloxapi/rbxfile"
+)
+
+// JournalEntryType is the type of a change recorded in a ReplicationJournal
+type JournalEntryType uint8
+
+const (
+	// JournalNewInstance records the creation of an instance
+	JournalNewInstance JournalEntryType = iota
+	// JournalProperty records a property change
+	JournalProperty
+	// JournalParent records a parent change
+	JournalParent
+	// JournalDelete records the deletion of an instance
+	JournalDelete
+)
+
+// JournalEntry describes one change applied to the DataModel
+type JournalEntry struct {
+	Type JournalEntryType
+	// UniqueID is the unique ID of the packet that caused the change
+	UniqueID uint64
+	Instance *datamodel.Instance
+	// Name is the name of the changed property
+	Name     string
+	OldValue rbxfile.Value
+	NewValue rbxfile.Value
+	// OldParent and NewParent are set for all types except JournalProperty
+	OldParent *datamodel.Instance
+	NewParent *datamodel.Instance
+}
+
+// DefaultReplicationJournalLimit is the default number of entries kept by a ReplicationJournal
+const DefaultReplicationJournalLimit = 1000000
+
+// ReplicationJournal records the changes that have been applied to
+// a CommunicationContext's DataModel, allowing the DataModel to be
+// rewound to an earlier packet
+type ReplicationJournal struct {
+	mutex sync.Mutex
+	// Limit is the maximum number of entries kept. When it is exceeded,
+	// the oldest entries are dropped and the DataModel can no longer be
+	// rewound past them. If it is zero or negative, all entries are kept.
+	Limit   int
+	entries []*JournalEntry
+	// droppedID is the greatest unique ID among the dropped entries
+	droppedID uint64
+}
+
+// NewReplicationJournal returns an empty ReplicationJournal that keeps
+// at most limit entries
+func NewReplicationJournal(limit int) *ReplicationJournal {
+	return &ReplicationJournal{Limit: limit}
+}
+
+func (journal *ReplicationJournal) record(entry *JournalEntry) {
+	if journal == nil {
+		return
+	}
+	journal.mutex.Lock()
+	journal.entries = append(journal.entries, entry)
+	if journal.Limit > 0 && len(journal.entries) > journal.Limit {
+		dropped := len(journal.entries) - journal.Limit
+		for i := 0; i < dropped; i++ {
+			if journal.entries[i].UniqueID > journal.droppedID {
+				journal.droppedID = journal.entries[i].UniqueID
+			}
+			journal.entries[i] = nil
+		}
+		// The dropped entries are released when append reallocates
+		journal.entries = journal.entries[dropped:]
+	}
+	journal.mutex.Unlock()
+}
+
+func (journal *ReplicationJournal) recordParent(uniqueID uint64, entryType JournalEntryType, instance *datamodel.Instance, oldParent *datamodel.Instance, newParent *datamodel.Instance) {
+	journal.record(&JournalEntry{
+		Type:      entryType,
+		UniqueID:  uniqueID,
+		Instance:  instance,
+		OldParent: oldParent,
+		NewParent: newParent,
+	})
+}
+
+// recordDestroy records the removal of the instance and its descendants.
+// Children are recorded in reverse order so that undoing the entries
+// restores them in their original order.
+func (journal *ReplicationJournal) recordDestroy(uniqueID uint64, instance *datamodel.Instance) {
+	if journal == nil {
+		return
+	}
+	journal.recordParent(uniqueID, JournalDelete, instance, instance.Parent(), nil)
+	for i := len(instance.Children) - 1; i >= 0; i-- {
+		journal.recordDestroy(uniqueID, instance.Children[i])
+	}
+}
+
+func (journal *ReplicationJournal) recordProperty(uniqueID uint64, instance *datamodel.Instance, name string, oldValue rbxfile.Value, newValue rbxfile.Value) {
+	journal.record(&JournalEntry{
+		Type:     JournalProperty,
+		UniqueID: uniqueID,
+		Instance: instance,
+		Name:     name,
+		OldValue: oldValue,
+		NewValue: newValue,
+	})
+}
+
+// Entries returns a copy of the recorded entries in the order
+// they were applied
+func (journal *ReplicationJournal) Entries() []*JournalEntry {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+	return append([]*JournalEntry(nil), journal.entries...)
+}
+
+// UniqueIDs returns the sorted unique IDs of the packets that changed the DataModel.
+// Only packets that the DataModel can be rewound to are included.
+func (journal *ReplicationJournal) UniqueIDs() []uint64 {
+	journal.mutex.Lock()
+	seen := make(map[uint64]struct{})
+	ids := make([]uint64, 0)
+	for _, entry := range journal.entries {
+		if entry.UniqueID < journal.droppedID {
+			continue
+		}
+		if _, ok := seen[entry.UniqueID]; !ok {
+			seen[entry.UniqueID] = struct{}{}
+			ids = append(ids, entry.UniqueID)
+		}
+	}
+	journal.mutex.Unlock()
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// snapshotBuilder lazily copies instances of the live DataModel
+// so that changes can be undone without affecting it
+type snapshotBuilder struct {
+	copies map[*datamodel.Instance]*datamodel.Instance
+}
+
+func (builder *snapshotBuilder) copyOf(instance *datamodel.Instance) *datamodel.Instance {
+	if instance == nil {
+		return nil
+	}
+	if copied, ok := builder.copies[instance]; ok {
+		return copied
+	}
+	copied, _ := datamodel.NewInstance(instance.ClassName, nil)
+	copied.Ref = instance.Ref
+	copied.IsService = instance.IsService
+	builder.copies[instance] = copied
+	instance.PropertiesMutex.RLock()
+	for name, value := range instance.Properties {
+		copied.Properties[name] = value
+	}
+	instance.PropertiesMutex.RUnlock()
+	for _, child := range instance.Children {
+		copied.AddChild(builder.copyOf(child))
+	}
+	return copied
+}
+
+// fixReferences makes the reference properties of the copies point to other copies
+func (builder *snapshotBuilder) fixReferences() {
+	for _, copied := range builder.copies {
+		for name, value := range copied.Properties {
+			ref, ok := value.(datamodel.ValueReference)
+			if !ok || ref.Instance == nil {
+				continue
+			}
+			if target, ok := builder.copies[ref.Instance]; ok {
+				copied.Properties[name] = datamodel.ValueReference{Instance: target, Reference: ref.Reference}
+			}
+		}
+	}
+}
+
+func (builder *snapshotBuilder) undo(entry *JournalEntry) {
+	instance := builder.copyOf(entry.Instance)
+	switch entry.Type {
+	case JournalProperty:
+		if entry.OldValue == nil {
+			delete(instance.Properties, entry.Name)
+		} else {
+			instance.Properties[entry.Name] = entry.OldValue
+		}
+	default:
+		instance.SetParent(builder.copyOf(entry.OldParent))
+	}
+}
+
+// Snapshot returns a copy of the DataModel as it was right after the packet
+// with the given unique ID had been applied. model should be the DataModel
+// that the journal was recorded for. Services are never removed from
+// the snapshot. If entries after the packet have been dropped because of
+// Limit, the snapshot is only partially rewound.
+func (journal *ReplicationJournal) Snapshot(model *datamodel.DataModel, uniqueID uint64) *datamodel.DataModel {
+	entries := journal.Entries()
+
+	builder := &snapshotBuilder{copies: make(map[*datamodel.Instance]*datamodel.Instance)}
+	snapshot := datamodel.New()
+	for _, service := range model.Instances {
+		snapshot.Instances = append(snapshot.Instances, builder.copyOf(service))
+	}
+	// Ordered packets may be applied after packets with a greater unique ID,
+	// so every entry must be checked
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].UniqueID > uniqueID {
+			builder.undo(entries[i])
+		}
+	}
+	builder.fixReferences()
+
+	return snapshot
+}
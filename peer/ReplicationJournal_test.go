@@ -0,0 +1,37 @@
+package peer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/robloxapi/rbxfile"
+)
+
+func TestReplicationJournalLimit(t *testing.T) {
+	model := datamodel.New()
+	workspace, _ := datamodel.NewInstance("Workspace", nil)
+	workspace.IsService = true
+	model.AddService(workspace)
+	part, _ := datamodel.NewInstance("Part", workspace)
+
+	journal := NewReplicationJournal(3)
+	// Packet 2 is applied before packet 1
+	for _, uniqueID := range []uint64{2, 1, 3, 4, 5} {
+		value := rbxfile.ValueInt(uniqueID)
+		journal.recordProperty(uniqueID, part, "Value", part.Get("Value"), value)
+		part.Set("Value", value)
+	}
+
+	if entries := journal.Entries(); len(entries) != 3 || entries[0].UniqueID != 3 {
+		t.Fatalf("oldest entries weren't dropped: %d entries", len(entries))
+	}
+	// The DataModel can't be rewound past packet 2
+	if ids := journal.UniqueIDs(); !reflect.DeepEqual(ids, []uint64{3, 4, 5}) {
+		t.Errorf("unexpected unique IDs %v", ids)
+	}
+	snapshot := journal.Snapshot(model, 4)
+	if value := snapshot.Instances[0].Children[0].Get("Value"); value != rbxfile.ValueInt(4) {
+		t.Errorf("snapshot has value %v, expected 4", value)
+	}
+}
@@ -0,0 +1,256 @@
+package peer
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+)
+
+// accessorTypes maps network value types to the Go types that
+// the values are decoded as
+var accessorTypes = map[uint8]string{
+	PropertyTypeNil:                    "rbxfile.Value",
+	PropertyTypeString:                 "rbxfile.ValueString",
+	PropertyTypeStringNoCache:          "rbxfile.ValueString",
+	PropertyTypeProtectedString0:       "rbxfile.ValueProtectedString",
+	PropertyTypeProtectedString1:       "rbxfile.ValueProtectedString",
+	PropertyTypeProtectedString2:       "rbxfile.ValueProtectedString",
+	PropertyTypeProtectedString3:       "rbxfile.ValueProtectedString",
+	PropertyTypeEnum:                   "datamodel.ValueToken",
+	PropertyTypeBinaryString:           "rbxfile.ValueBinaryString",
+	PropertyTypeBool:                   "rbxfile.ValueBool",
+	PropertyTypeInt:                    "rbxfile.ValueInt",
+	PropertyTypeFloat:                  "rbxfile.ValueFloat",
+	PropertyTypeDouble:                 "rbxfile.ValueDouble",
+	PropertyTypeUDim:                   "rbxfile.ValueUDim",
+	PropertyTypeUDim2:                  "rbxfile.ValueUDim2",
+	PropertyTypeRay:                    "rbxfile.ValueRay",
+	PropertyTypeFaces:                  "rbxfile.ValueFaces",
+	PropertyTypeAxes:                   "rbxfile.ValueAxes",
+	PropertyTypeBrickColor:             "rbxfile.ValueBrickColor",
+	PropertyTypeColor3:                 "rbxfile.ValueColor3",
+	PropertyTypeColor3uint8:            "rbxfile.ValueColor3uint8",
+	PropertyTypeVector2:                "rbxfile.ValueVector2",
+	PropertyTypeSimpleVector3:          "rbxfile.ValueVector3",
+	PropertyTypeComplicatedVector3:     "rbxfile.ValueVector3",
+	PropertyTypeVector2int16:           "rbxfile.ValueVector2int16",
+	PropertyTypeVector3int16:           "rbxfile.ValueVector3int16",
+	PropertyTypeSimpleCFrame:           "rbxfile.ValueCFrame",
+	PropertyTypeComplicatedCFrame:      "rbxfile.ValueCFrame",
+	PropertyTypeInstance:               "datamodel.ValueReference",
+	PropertyTypeTuple:                  "datamodel.ValueTuple",
+	PropertyTypeArray:                  "datamodel.ValueArray",
+	PropertyTypeDictionary:             "datamodel.ValueDictionary",
+	PropertyTypeMap:                    "datamodel.ValueMap",
+	PropertyTypeContent:                "rbxfile.ValueContent",
+	PropertyTypeSystemAddress:          "datamodel.ValueSystemAddress",
+	PropertyTypeNumberSequence:         "datamodel.ValueNumberSequence",
+	PropertyTypeNumberSequenceKeypoint: "datamodel.ValueNumberSequenceKeypoint",
+	PropertyTypeNumberRange:            "rbxfile.ValueNumberRange",
+	PropertyTypeColorSequence:          "datamodel.ValueColorSequence",
+	PropertyTypeColorSequenceKeypoint:  "datamodel.ValueColorSequenceKeypoint",
+	PropertyTypeRect2D:                 "rbxfile.ValueRect2D",
+	PropertyTypePhysicalProperties:     "rbxfile.ValuePhysicalProperties",
+	PropertyTypeRegion3:                "datamodel.ValueRegion3",
+	PropertyTypeRegion3int16:           "datamodel.ValueRegion3int16",
+	PropertyTypeInt64:                  "rbxfile.ValueInt64",
+	PropertyTypePathWaypoint:           "datamodel.ValuePathWaypoint",
+	PropertyTypeSharedString:           "*datamodel.ValueDeferredString",
+	PropertyTypeLuauString:             "datamodel.ValueSignedProtectedString",
+	PropertyTypeDateTime:               "datamodel.ValueDateTime",
+	PropertyTypeOptimizedString:        "rbxfile.ValueString",
+}
+
+func accessorType(valueType uint8) string {
+	goType, ok := accessorTypes[valueType]
+	if !ok {
+		return "rbxfile.Value"
+	}
+	return goType
+}
+
+// accessorName converts a schema name to an exported Go identifier
+func accessorName(name string) string {
+	var builder strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			builder.WriteRune(r)
+		}
+	}
+	runes := []rune(builder.String())
+	if len(runes) == 0 || !unicode.IsLetter(runes[0]) {
+		runes = append([]rune("X"), runes...)
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// accessorNames keeps track of the identifiers that have been used
+type accessorNames map[string]bool
+
+// claim returns the identifier prefix+name+suffix, renaming it if
+// it or one of the other prefixes is taken. The identifier is then
+// reserved together with the identifiers for the other prefixes.
+func (names accessorNames) claim(name string, prefixes ...string) string {
+	candidate := name
+	for n := 2; ; n++ {
+		free := true
+		for _, prefix := range prefixes {
+			if names[prefix+candidate] {
+				free = false
+				break
+			}
+		}
+		if free {
+			break
+		}
+		candidate = fmt.Sprintf("%s%d", name, n)
+	}
+	for _, prefix := range prefixes {
+		names[prefix+candidate] = true
+	}
+	return candidate
+}
+
+// instanceMemberNames returns the names of the exported fields and methods
+// of *datamodel.Instance. Generated wrappers embed it, so their methods
+// must not shadow these names.
+func instanceMemberNames() accessorNames {
+	names := accessorNames{"Instance": true}
+	instanceType := reflect.TypeOf(&datamodel.Instance{})
+	for i := 0; i < instanceType.NumMethod(); i++ {
+		names[instanceType.Method(i).Name] = true
+	}
+	structType := instanceType.Elem()
+	for i := 0; i < structType.NumField(); i++ {
+		if field := structType.Field(i); field.PkgPath == "" {
+			names[field.Name] = true
+		}
+	}
+	return names
+}
+
+// accessorGenerator writes the source code of the accessors
+type accessorGenerator struct {
+	body    bytes.Buffer
+	imports map[string]bool
+}
+
+func (gen *accessorGenerator) useType(goType string) {
+	if strings.Contains(goType, "rbxfile.") {
+		gen.imports["github.com/robloxapi/rbxfile"] = true
+	}
+}
+
+func (gen *accessorGenerator) writeClass(class *NetworkInstanceSchema, typeName string) {
+	body := &gen.body
+	fmt.Fprintf(body, "// %s wraps an instance of the class %s\n", typeName, class.Name)
+	fmt.Fprintf(body, "type %s struct {\n\t*datamodel.Instance\n}\n\n", typeName)
+
+	names := instanceMemberNames()
+	for _, prop := range class.Properties {
+		name := names.claim(accessorName(prop.Name), "", "Set")
+		goType := accessorType(prop.Type)
+		gen.useType(goType)
+
+		fmt.Fprintf(body, "// %s returns the value of %s.%s\n", name, class.Name, prop.Name)
+		fmt.Fprintf(body, "func (inst %s) %s() %s {\n", typeName, name, goType)
+		if goType == "rbxfile.Value" {
+			fmt.Fprintf(body, "\treturn inst.Instance.Get(%q)\n}\n\n", prop.Name)
+		} else {
+			fmt.Fprintf(body, "\tvalue, _ := inst.Instance.Get(%q).(%s)\n\treturn value\n}\n\n", prop.Name, goType)
+		}
+		fmt.Fprintf(body, "// Set%s sets the value of %s.%s\n", name, class.Name, prop.Name)
+		fmt.Fprintf(body, "func (inst %s) Set%s(value %s) {\n\tinst.Instance.Set(%q, value)\n}\n\n", typeName, name, goType, prop.Name)
+	}
+
+	for _, event := range class.Events {
+		name := names.claim(accessorName(event.Name), "Fire", "On")
+		params := make([]string, len(event.Arguments))
+		args := make([]string, len(event.Arguments))
+		for i, argument := range event.Arguments {
+			goType := accessorType(argument.Type)
+			gen.useType(goType)
+			args[i] = fmt.Sprintf("arg%d", i+1)
+			params[i] = args[i] + " " + goType
+		}
+		gen.imports["github.com/olebedev/emitter"] = true
+
+		fmt.Fprintf(body, "// Fire%s fires %s.%s\n", name, class.Name, event.Name)
+		fmt.Fprintf(body, "func (inst %s) Fire%s(%s) {\n", typeName, name, strings.Join(params, ", "))
+		fmt.Fprintf(body, "\tinst.Instance.FireEvent(%q", event.Name)
+		for _, arg := range args {
+			fmt.Fprintf(body, ", %s", arg)
+		}
+		fmt.Fprintf(body, ")\n}\n\n")
+
+		fmt.Fprintf(body, "// On%s calls handler every time %s.%s is fired.\n", name, class.Name, event.Name)
+		fmt.Fprintf(body, "// The returned channel can be passed to EventEmitter.Off() to remove the handler.\n")
+		fmt.Fprintf(body, "func (inst %s) On%s(handler func(%s)) <-chan emitter.Event {\n", typeName, name, strings.Join(params, ", "))
+		fmt.Fprintf(body, "\treturn inst.Instance.EventEmitter.On(%q, func(e *emitter.Event) {\n", event.Name)
+		if len(event.Arguments) != 0 {
+			gen.imports["github.com/robloxapi/rbxfile"] = true
+			fmt.Fprintf(body, "\t\targs := e.Args[0].([]rbxfile.Value)\n")
+		}
+		for i, argument := range event.Arguments {
+			goType := accessorType(argument.Type)
+			fmt.Fprintf(body, "\t\tvar %s %s\n", args[i], goType)
+			fmt.Fprintf(body, "\t\tif len(args) > %d {\n", i)
+			if goType == "rbxfile.Value" {
+				fmt.Fprintf(body, "\t\t\t%s = args[%d]\n", args[i], i)
+			} else {
+				fmt.Fprintf(body, "\t\t\t%s, _ = args[%d].(%s)\n", args[i], i, goType)
+			}
+			fmt.Fprintf(body, "\t\t}\n")
+		}
+		fmt.Fprintf(body, "\t\thandler(%s)\n", strings.Join(args, ", "))
+		fmt.Fprintf(body, "\t}, emitter.Void)\n}\n\n")
+	}
+}
+
+// GenerateAccessors writes the source code of a Go package that contains a
+// typed wrapper for each class in the schema. The wrappers embed
+// *datamodel.Instance and have a getter and a setter for each property and
+// Fire and On methods for each event, for example:
+//
+//	part := Part{instance}
+//	part.SetSize(part.Size())
+func (schema *NetworkSchema) GenerateAccessors(w io.Writer, packageName string) error {
+	gen := &accessorGenerator{
+		imports: map[string]bool{
+			"github.com/Gskartwii/roblox-dissector/datamodel": true,
+		},
+	}
+	typeNames := accessorNames{}
+	for _, class := range schema.Instances {
+		gen.writeClass(class, typeNames.claim(accessorName(class.Name), ""))
+	}
+
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "// Code generated by schema-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n\nimport (\n", packageName)
+	for _, path := range []string{
+		"github.com/Gskartwii/roblox-dissector/datamodel",
+		"github.com/olebedev/emitter",
+		"github.com/robloxapi/rbxfile",
+	} {
+		if gen.imports[path] {
+			fmt.Fprintf(&source, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintf(&source, ")\n\n")
+	source.Write(gen.body.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
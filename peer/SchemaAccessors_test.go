@@ -0,0 +1,96 @@
+package peer
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// accessorTestSchema contains names that collide with
+// the members of *datamodel.Instance
+const accessorTestSchema = `1
+"NormalId" 3
+2 5 3
+"BindableEvent" 0
+	2
+	"Name" 1 0
+	"Instance" 28 0
+	1
+	"Event" 1
+		29 0
+"Part" 0
+	3
+	"Name" 1 0
+	"Shape" 7 0
+	"size" 1 0
+	2
+	"Touched" 1
+		28 0
+	"Destroying" 0
+1
+"rbxassetid://"
+1
+"Humanoid"
+`
+
+func TestGenerateAccessors(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(accessorTestSchema))
+	if err != nil {
+		t.Fatalf("failed to parse schema: %s", err.Error())
+	}
+
+	var source bytes.Buffer
+	err = schema.GenerateAccessors(&source, "instances")
+	if err != nil {
+		t.Fatalf("failed to generate accessors: %s", err.Error())
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "instances.go", source.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("failed to parse generated source: %s\n%s", err.Error(), source.String())
+	}
+	config := &types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := config.Check("instances", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("generated source doesn't type-check: %s\n%s", err.Error(), source.String())
+	}
+
+	expectedMethods := map[string]string{
+		"Part.Shape":                 "func() github.com/Gskartwii/roblox-dissector/datamodel.ValueToken",
+		"Part.Size":                  "func() github.com/robloxapi/rbxfile.ValueString",
+		"Part.FireTouched":           "func(arg1 github.com/Gskartwii/roblox-dissector/datamodel.ValueReference)",
+		"BindableEvent.FireEvent2":   "func(arg1 github.com/Gskartwii/roblox-dissector/datamodel.ValueTuple)",
+		"BindableEvent.Instance2":    "func() github.com/Gskartwii/roblox-dissector/datamodel.ValueReference",
+		"BindableEvent.Name2":        "func() github.com/robloxapi/rbxfile.ValueString",
+		"BindableEvent.SetName2":     "func(value github.com/robloxapi/rbxfile.ValueString)",
+		"Part.FireDestroying":        "func()",
+		"BindableEvent.FireEvent":    "func(name string, args ...github.com/robloxapi/rbxfile.Value)",
+		"BindableEvent.Name":         "func() string",
+		"Part.WaitForChild":          "func(ctx context.Context, names ...string) (*github.com/Gskartwii/roblox-dissector/datamodel.Instance, error)",
+		"BindableEvent.OnEvent2":     "func(handler func(arg1 github.com/Gskartwii/roblox-dissector/datamodel.ValueTuple)) <-chan github.com/olebedev/emitter.Event",
+		"Part.OnDestroying":          "func(handler func()) <-chan github.com/olebedev/emitter.Event",
+		"BindableEvent.SetInstance2": "func(value github.com/Gskartwii/roblox-dissector/datamodel.ValueReference)",
+	}
+	for name, expected := range expectedMethods {
+		parts := strings.SplitN(name, ".", 2)
+		typeName := pkg.Scope().Lookup(parts[0])
+		if typeName == nil {
+			t.Errorf("type %s wasn't generated", parts[0])
+			continue
+		}
+		method, _, _ := types.LookupFieldOrMethod(typeName.Type(), false, pkg, parts[1])
+		if method == nil {
+			t.Errorf("method %s wasn't generated", name)
+			continue
+		}
+		if signature := method.Type().String(); signature != expected {
+			t.Errorf("%s has signature %s, expected %s", name, signature, expected)
+		}
+	}
+}
@@ -85,7 +85,7 @@ func ParseSchema(schemafile io.Reader) (*NetworkSchema, error) {
 				Name:           property[1],
 				Type:           uint8(mustAtoi(property[2])),
 				EnumID:         uint16(mustAtoi(property[3])),
-				TypeString:     TypeNames[uint8(mustAtoi(property[2]))],
+				TypeString:     typeString(uint8(mustAtoi(property[2]))),
 				InstanceSchema: thisInstance,
 				NetworkID:      uint16(propertyGlobalIndex),
 			}
@@ -125,7 +125,7 @@ func ParseSchema(schemafile io.Reader) (*NetworkSchema, error) {
 				}
 				thisArgument := &NetworkArgumentSchema{
 					Type:       uint8(argType),
-					TypeString: TypeNames[uint8(argType)],
+					TypeString: typeString(uint8(argType)),
 					EnumID:     uint16(argUnk),
 				}
 
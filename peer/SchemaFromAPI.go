@@ -0,0 +1,328 @@
+package peer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+)
+
+// DefaultAPITypeMapping maps value type names used by the Roblox API dump
+// to the network value types that are most commonly used for them
+var DefaultAPITypeMapping = map[string]uint8{
+	"string":                 PropertyTypeString,
+	"bool":                   PropertyTypeBool,
+	"int":                    PropertyTypeInt,
+	"int64":                  PropertyTypeInt64,
+	"float":                  PropertyTypeFloat,
+	"double":                 PropertyTypeDouble,
+	"BinaryString":           PropertyTypeBinaryString,
+	"ProtectedString":        PropertyTypeLuauString,
+	"SharedString":           PropertyTypeSharedString,
+	"Content":                PropertyTypeContent,
+	"UDim":                   PropertyTypeUDim,
+	"UDim2":                  PropertyTypeUDim2,
+	"Ray":                    PropertyTypeRay,
+	"Faces":                  PropertyTypeFaces,
+	"Axes":                   PropertyTypeAxes,
+	"BrickColor":             PropertyTypeBrickColor,
+	"Color3":                 PropertyTypeColor3,
+	"Color3uint8":            PropertyTypeColor3uint8,
+	"Vector2":                PropertyTypeVector2,
+	"Vector3":                PropertyTypeSimpleVector3,
+	"Vector2int16":           PropertyTypeVector2int16,
+	"Vector3int16":           PropertyTypeVector3int16,
+	"CFrame":                 PropertyTypeComplicatedCFrame,
+	"Tuple":                  PropertyTypeTuple,
+	"Array":                  PropertyTypeArray,
+	"Dictionary":             PropertyTypeDictionary,
+	"Map":                    PropertyTypeMap,
+	"NumberSequence":         PropertyTypeNumberSequence,
+	"NumberSequenceKeypoint": PropertyTypeNumberSequenceKeypoint,
+	"NumberRange":            PropertyTypeNumberRange,
+	"ColorSequence":          PropertyTypeColorSequence,
+	"ColorSequenceKeypoint":  PropertyTypeColorSequenceKeypoint,
+	"Rect":                   PropertyTypeRect2D,
+	"PhysicalProperties":     PropertyTypePhysicalProperties,
+	"Region3":                PropertyTypeRegion3,
+	"Region3int16":           PropertyTypeRegion3int16,
+	"PathWaypoint":           PropertyTypePathWaypoint,
+	"DateTime":               PropertyTypeDateTime,
+	"Variant":                PropertyTypeTuple,
+	"Objects":                PropertyTypeArray,
+}
+
+// MergeAPITypeMapping returns a copy of DefaultAPITypeMapping
+// in which the entries of overrides have been replaced or added
+func MergeAPITypeMapping(overrides map[string]uint8) map[string]uint8 {
+	typeMapping := make(map[string]uint8, len(DefaultAPITypeMapping)+len(overrides))
+	for name, thisType := range DefaultAPITypeMapping {
+		typeMapping[name] = thisType
+	}
+	for name, thisType := range overrides {
+		typeMapping[name] = thisType
+	}
+	return typeMapping
+}
+
+// ParseAPITypeMapping parses a JSON object that maps API value type names
+// to PropertyType* constants, for example {"Content": 1}.
+// The entries are merged with DefaultAPITypeMapping.
+func ParseAPITypeMapping(file io.Reader) (map[string]uint8, error) {
+	var overrides map[string]uint8
+	err := json.NewDecoder(file).Decode(&overrides)
+	if err != nil {
+		return nil, err
+	}
+	for name, thisType := range overrides {
+		if _, ok := TypeNames[thisType]; !ok {
+			return nil, fmt.Errorf("unknown type %d for %s", thisType, name)
+		}
+	}
+	return MergeAPITypeMapping(overrides), nil
+}
+
+// DefaultContentPrefixes is the list of content prefixes used by
+// schemas generated from an API dump
+var DefaultContentPrefixes = []string{
+	"rbxasset://",
+	"rbxassetid://",
+	"rbxhttp://",
+	"http://www.roblox.com/asset/?id=",
+	"https://www.roblox.com/asset/?id=",
+}
+
+// SchemaMember describes a class member that is present in
+// one schema but not in another
+type SchemaMember struct {
+	ClassName  string
+	MemberName string
+	// MemberType is either "Property" or "Event"
+	MemberType string
+}
+
+func (member SchemaMember) String() string {
+	return fmt.Sprintf("%s %s.%s", member.MemberType, member.ClassName, member.MemberName)
+}
+
+func apiTypeToNetworkType(valueType rbxapijson.Type, typeMapping map[string]uint8) (uint8, bool) {
+	switch valueType.Category {
+	case "Enum":
+		return PropertyTypeEnum, true
+	case "Class":
+		return PropertyTypeInstance, true
+	}
+	thisType, ok := typeMapping[valueType.Name]
+	return thisType, ok
+}
+
+func enumBitSize(enum *rbxapijson.Enum) uint8 {
+	var maxValue uint
+	for _, item := range enum.Items {
+		if item.Value > 0 && uint(item.Value) > maxValue {
+			maxValue = uint(item.Value)
+		}
+	}
+	if maxValue == 0 {
+		return 1
+	}
+	return uint8(bits.Len(maxValue))
+}
+
+// apiMembers returns the members of a class, including inherited ones
+func apiMembers(api *rbxapijson.Root, class *rbxapijson.Class) []rbxapi.Member {
+	var members []rbxapi.Member
+	for class != nil {
+		members = append(members, class.Members...)
+		superclass, _ := api.GetClass(class.Superclass).(*rbxapijson.Class)
+		class = superclass
+	}
+	return members
+}
+
+// SchemaFromAPIDump generates a best-effort NetworkSchema based on
+// a Roblox API dump. typeMapping maps API value type names to
+// PropertyType* constants; if it is nil, DefaultAPITypeMapping is used.
+// Members that can't be replicated, as well as members whose type can't be mapped,
+// will be skipped. A description of each skipped member is returned in the
+// second return value.
+func SchemaFromAPIDump(api *rbxapijson.Root, typeMapping map[string]uint8) (*NetworkSchema, []string) {
+	if typeMapping == nil {
+		typeMapping = DefaultAPITypeMapping
+	}
+	var warnings []string
+	schema := &NetworkSchema{
+		ContentPrefixes:  append([]string(nil), DefaultContentPrefixes...),
+		OptimizedStrings: []string{},
+	}
+
+	enums := append([]*rbxapijson.Enum(nil), api.Enums...)
+	sort.Slice(enums, func(i, j int) bool {
+		return enums[i].Name < enums[j].Name
+	})
+	schema.Enums = make([]*NetworkEnumSchema, len(enums))
+	for i, enum := range enums {
+		schema.Enums[i] = &NetworkEnumSchema{
+			Name:      enum.Name,
+			BitSize:   enumBitSize(enum),
+			NetworkID: uint16(i),
+		}
+	}
+	schema.LoadEnumItems(api)
+	enumID := func(valueType rbxapijson.Type) uint16 {
+		if valueType.Category != "Enum" {
+			return 0
+		}
+		enum := schema.SchemaForEnum(valueType.Name)
+		if enum == nil {
+			warnings = append(warnings, fmt.Sprintf("unknown enum %s", valueType.Name))
+			return 0
+		}
+		return enum.NetworkID
+	}
+
+	classes := append([]*rbxapijson.Class(nil), api.Classes...)
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Name < classes[j].Name
+	})
+	schema.Instances = make([]*NetworkInstanceSchema, len(classes))
+	for i, class := range classes {
+		thisInstance := &NetworkInstanceSchema{
+			Name:      class.Name,
+			NetworkID: uint16(i),
+		}
+
+		var properties []*rbxapijson.Property
+		var events []*rbxapijson.Event
+		for _, member := range apiMembers(api, class) {
+			switch member := member.(type) {
+			case *rbxapijson.Property:
+				// Parent is replicated using a special schema
+				if member.Name == "Parent" || member.GetTag("NotReplicated") {
+					continue
+				}
+				properties = append(properties, member)
+			case *rbxapijson.Event:
+				if member.GetTag("NotReplicated") {
+					continue
+				}
+				events = append(events, member)
+			}
+		}
+		sort.Slice(properties, func(i, j int) bool {
+			return properties[i].Name < properties[j].Name
+		})
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].Name < events[j].Name
+		})
+
+		for _, property := range properties {
+			thisType, ok := apiTypeToNetworkType(property.ValueType, typeMapping)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("skipping property %s.%s: unknown type %s", class.Name, property.Name, property.ValueType.String()))
+				continue
+			}
+			thisProperty := &NetworkPropertySchema{
+				Name:           property.Name,
+				Type:           thisType,
+				TypeString:     typeString(thisType),
+				EnumID:         enumID(property.ValueType),
+				InstanceSchema: thisInstance,
+				NetworkID:      uint16(len(schema.Properties)),
+			}
+			thisInstance.Properties = append(thisInstance.Properties, thisProperty)
+			schema.Properties = append(schema.Properties, thisProperty)
+		}
+
+	eventLoop:
+		for _, event := range events {
+			thisEvent := &NetworkEventSchema{
+				Name:           event.Name,
+				Arguments:      make([]*NetworkArgumentSchema, len(event.Parameters)),
+				InstanceSchema: thisInstance,
+			}
+			for j, parameter := range event.Parameters {
+				thisType, ok := apiTypeToNetworkType(parameter.Type, typeMapping)
+				if !ok {
+					warnings = append(warnings, fmt.Sprintf("skipping event %s.%s: unknown argument type %s", class.Name, event.Name, parameter.Type.String()))
+					continue eventLoop
+				}
+				thisEvent.Arguments[j] = &NetworkArgumentSchema{
+					Type:       thisType,
+					TypeString: typeString(thisType),
+					EnumID:     enumID(parameter.Type),
+				}
+			}
+			thisEvent.NetworkID = uint16(len(schema.Events))
+			thisInstance.Events = append(thisInstance.Events, thisEvent)
+			schema.Events = append(schema.Events, thisEvent)
+		}
+
+		schema.Instances[i] = thisInstance
+	}
+
+	return schema, warnings
+}
+
+// LoadEnumItems populates the Items of the schema's enums
+// based on the enum items listed in the API dump
+func (schema *NetworkSchema) LoadEnumItems(api *rbxapijson.Root) {
+	for _, enum := range schema.Enums {
+		apiEnum, _ := api.GetEnum(enum.Name).(*rbxapijson.Enum)
+		if apiEnum == nil {
+			continue
+		}
+		enum.Items = make([]*NetworkEnumItemSchema, 0, len(apiEnum.Items))
+		for _, item := range apiEnum.Items {
+			enum.Items = append(enum.Items, &NetworkEnumItemSchema{
+				Name:  item.Name,
+				Value: uint32(item.Value),
+			})
+		}
+	}
+}
+
+// MembersMissingFromAPI returns the replicated members of the schema
+// which don't exist in the given API dump
+func (schema *NetworkSchema) MembersMissingFromAPI(api *rbxapijson.Root) []SchemaMember {
+	var missing []SchemaMember
+	for _, instance := range schema.Instances {
+		class, _ := api.GetClass(instance.Name).(*rbxapijson.Class)
+		apiMemberSet := make(map[string]bool)
+		if class != nil {
+			for _, member := range apiMembers(api, class) {
+				switch member := member.(type) {
+				case *rbxapijson.Property:
+					apiMemberSet["Property "+member.Name] = true
+				case *rbxapijson.Event:
+					apiMemberSet["Event "+member.Name] = true
+				}
+			}
+		}
+
+		for _, property := range instance.Properties {
+			if !apiMemberSet["Property "+property.Name] {
+				missing = append(missing, SchemaMember{
+					ClassName:  instance.Name,
+					MemberName: property.Name,
+					MemberType: "Property",
+				})
+			}
+		}
+		for _, event := range instance.Events {
+			if !apiMemberSet["Event "+event.Name] {
+				missing = append(missing, SchemaMember{
+					ClassName:  instance.Name,
+					MemberName: event.Name,
+					MemberType: "Event",
+				})
+			}
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,93 @@
+package peer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+)
+
+const testAPIDump = `{"Version":1,"Classes":[
+{"Name":"Instance","Superclass":"<<<ROOT>>>","MemoryCategory":"Instances","Members":[
+	{"MemberType":"Property","Name":"Name","ValueType":{"Category":"Primitive","Name":"string"},"Category":"Data","Security":{"Read":"None","Write":"None"},"Serialization":{"CanLoad":true,"CanSave":true}},
+	{"MemberType":"Property","Name":"Parent","ValueType":{"Category":"Class","Name":"Instance"},"Category":"Data","Security":{"Read":"None","Write":"None"},"Serialization":{"CanLoad":false,"CanSave":false},"Tags":["NotReplicated"]},
+	{"MemberType":"Event","Name":"Changed","Parameters":[{"Type":{"Category":"Primitive","Name":"string"},"Name":"property"}],"Security":"None"}
+]},
+{"Name":"Part","Superclass":"Instance","MemoryCategory":"BaseParts","Members":[
+	{"MemberType":"Property","Name":"Shape","ValueType":{"Category":"Enum","Name":"PartType"},"Category":"Part","Security":{"Read":"None","Write":"None"},"Serialization":{"CanLoad":true,"CanSave":true}},
+	{"MemberType":"Property","Name":"Weird","ValueType":{"Category":"DataType","Name":"Unsupported"},"Category":"Part","Security":{"Read":"None","Write":"None"},"Serialization":{"CanLoad":true,"CanSave":true}}
+]}],
+"Enums":[{"Name":"PartType","Items":[{"Name":"Ball","Value":0},{"Name":"Block","Value":1},{"Name":"Cylinder","Value":2}]}]}`
+
+func TestSchemaFromAPIDump(t *testing.T) {
+	schema, warnings, err := LoadSchema(strings.NewReader(testAPIDump))
+	if err != nil {
+		t.Fatalf("failed to generate schema: %s", err.Error())
+	}
+	if len(warnings) == 0 || !strings.Contains(strings.Join(warnings, "\n"), "Weird") {
+		t.Errorf("skipped property wasn't reported: %v", warnings)
+	}
+
+	part := schema.SchemaForClass("Part")
+	if part == nil {
+		t.Fatal("Part is missing from the schema")
+	}
+	if part.SchemaForProp("Name") == nil {
+		t.Error("Part doesn't inherit Name from Instance")
+	}
+	if part.SchemaForProp("Parent") != nil || part.SchemaForProp("Weird") != nil {
+		t.Error("schema contains properties that should have been skipped")
+	}
+	shape := part.SchemaForProp("Shape")
+	if shape == nil || shape.Type != PropertyTypeEnum || schema.Enums[shape.EnumID].Name != "PartType" {
+		t.Errorf("Part.Shape has an incorrect schema: %+v", shape)
+	}
+	if schema.Enums[0].BitSize != 2 {
+		t.Errorf("PartType has incorrect bit size %d", schema.Enums[0].BitSize)
+	}
+	token := schema.NameToken(datamodel.ValueToken{ID: shape.EnumID, Value: 2})
+	if token.String() != "Enum.PartType.Cylinder" {
+		t.Errorf("token has incorrect name %s", token.String())
+	}
+
+	schema.Instances[0].Events = append(schema.Instances[0].Events, &NetworkEventSchema{Name: "SecretEvent"})
+	// Missing members are computed against the same dump, so only the added event
+	// should be reported
+	api, err := rbxapijson.Decode(strings.NewReader(testAPIDump))
+	if err != nil {
+		t.Fatalf("failed to decode API dump: %s", err.Error())
+	}
+	missing := schema.MembersMissingFromAPI(api)
+	if len(missing) != 1 || missing[0].String() != "Event Instance.SecretEvent" {
+		t.Errorf("unexpected missing members: %v", missing)
+	}
+}
+
+func TestSchemaFromAPIDumpWithMapping(t *testing.T) {
+	typeMapping, err := ParseAPITypeMapping(strings.NewReader(`{"Unsupported": 8, "string": 2}`))
+	if err != nil {
+		t.Fatalf("failed to parse type mapping: %s", err.Error())
+	}
+	if typeMapping["bool"] != PropertyTypeBool {
+		t.Error("type mapping wasn't merged with the default mapping")
+	}
+	schema, warnings, err := LoadSchemaWithMapping(strings.NewReader(testAPIDump), typeMapping)
+	if err != nil {
+		t.Fatalf("failed to generate schema: %s", err.Error())
+	}
+	if strings.Contains(strings.Join(warnings, "\n"), "Weird") {
+		t.Errorf("mapped property was skipped: %v", warnings)
+	}
+	part := schema.SchemaForClass("Part")
+	if weird := part.SchemaForProp("Weird"); weird == nil || weird.Type != PropertyTypeBinaryString {
+		t.Errorf("Part.Weird has an incorrect schema: %+v", weird)
+	}
+	if name := part.SchemaForProp("Name"); name == nil || name.Type != PropertyTypeStringNoCache {
+		t.Errorf("Part.Name has an incorrect schema: %+v", name)
+	}
+
+	if _, err := ParseAPITypeMapping(strings.NewReader(`{"string": 250}`)); err == nil {
+		t.Error("unknown type was accepted")
+	}
+}
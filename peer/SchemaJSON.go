@@ -0,0 +1,305 @@
+package peer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"unicode"
+
+	"github.com/robloxapi/rbxapi/rbxapijson"
+)
+
+type jsonArgumentSchema struct {
+	Type       uint8  `json:"type"`
+	TypeString string `json:"typeString,omitempty"`
+	Enum       string `json:"enum,omitempty"`
+	EnumID     uint16 `json:"enumId,omitempty"`
+}
+
+type jsonEnumItemSchema struct {
+	Name  string `json:"name"`
+	Value uint32 `json:"value"`
+}
+
+type jsonEnumSchema struct {
+	Name    string                `json:"name"`
+	BitSize uint8                 `json:"bitSize"`
+	Items   []*jsonEnumItemSchema `json:"items,omitempty"`
+}
+
+type jsonEventSchema struct {
+	Name      string                `json:"name"`
+	Arguments []*jsonArgumentSchema `json:"arguments"`
+}
+
+type jsonPropertySchema struct {
+	Name       string `json:"name"`
+	Type       uint8  `json:"type"`
+	TypeString string `json:"typeString,omitempty"`
+	Enum       string `json:"enum,omitempty"`
+	EnumID     uint16 `json:"enumId,omitempty"`
+}
+
+type jsonInstanceSchema struct {
+	Name       string                `json:"name"`
+	Unknown    uint16                `json:"unknown"`
+	Properties []*jsonPropertySchema `json:"properties"`
+	Events     []*jsonEventSchema    `json:"events"`
+}
+
+type jsonNetworkSchema struct {
+	Enums            []*jsonEnumSchema     `json:"enums"`
+	Classes          []*jsonInstanceSchema `json:"classes"`
+	ContentPrefixes  []string              `json:"contentPrefixes"`
+	OptimizedStrings []string              `json:"optimizedStrings"`
+}
+
+// enumsNamed returns the number of enums with the given name
+func (schema *NetworkSchema) enumsNamed(name string) int {
+	count := 0
+	for _, enum := range schema.Enums {
+		if enum.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+// jsonEnumRef returns the values for the enum and enumId fields
+// The enum name is only used if it identifies the enum unambiguously
+func (schema *NetworkSchema) jsonEnumRef(thisType uint8, enumID uint16) (string, uint16) {
+	if thisType == PropertyTypeEnum && int(enumID) < len(schema.Enums) {
+		name := schema.Enums[enumID].Name
+		if schema.enumsNamed(name) == 1 {
+			return name, 0
+		}
+	}
+	return "", enumID
+}
+
+func (schema *NetworkSchema) resolveJSONEnumRef(enum string, enumID uint16) (uint16, error) {
+	if enum == "" {
+		return enumID, nil
+	}
+	switch schema.enumsNamed(enum) {
+	case 0:
+		return 0, fmt.Errorf("unknown enum %q", enum)
+	case 1:
+		return schema.SchemaForEnum(enum).NetworkID, nil
+	default:
+		return 0, fmt.Errorf("enum name %q is ambiguous, use enumId instead", enum)
+	}
+}
+
+// DumpJSON encodes a NetworkSchema to a JSON format that can be parsed by
+// ParseSchemaJSON(). Unlike Dump(), the output contains type names and
+// references enums by their names, so that it can be reviewed and edited by hand.
+func (schema *NetworkSchema) DumpJSON(file io.Writer) error {
+	output := &jsonNetworkSchema{
+		Enums:            make([]*jsonEnumSchema, len(schema.Enums)),
+		Classes:          make([]*jsonInstanceSchema, len(schema.Instances)),
+		ContentPrefixes:  schema.ContentPrefixes,
+		OptimizedStrings: schema.OptimizedStrings,
+	}
+	for i, enum := range schema.Enums {
+		thisEnum := &jsonEnumSchema{
+			Name:    enum.Name,
+			BitSize: enum.BitSize,
+		}
+		for _, item := range enum.Items {
+			thisEnum.Items = append(thisEnum.Items, &jsonEnumItemSchema{
+				Name:  item.Name,
+				Value: item.Value,
+			})
+		}
+		output.Enums[i] = thisEnum
+	}
+	for i, instance := range schema.Instances {
+		thisInstance := &jsonInstanceSchema{
+			Name:       instance.Name,
+			Unknown:    instance.Unknown,
+			Properties: make([]*jsonPropertySchema, len(instance.Properties)),
+			Events:     make([]*jsonEventSchema, len(instance.Events)),
+		}
+		for j, property := range instance.Properties {
+			thisProperty := &jsonPropertySchema{
+				Name:       property.Name,
+				Type:       property.Type,
+				TypeString: typeString(property.Type),
+			}
+			thisProperty.Enum, thisProperty.EnumID = schema.jsonEnumRef(property.Type, property.EnumID)
+			thisInstance.Properties[j] = thisProperty
+		}
+		for j, event := range instance.Events {
+			thisEvent := &jsonEventSchema{
+				Name:      event.Name,
+				Arguments: make([]*jsonArgumentSchema, len(event.Arguments)),
+			}
+			for k, argument := range event.Arguments {
+				thisArgument := &jsonArgumentSchema{
+					Type:       argument.Type,
+					TypeString: typeString(argument.Type),
+				}
+				thisArgument.Enum, thisArgument.EnumID = schema.jsonEnumRef(argument.Type, argument.EnumID)
+				thisEvent.Arguments[k] = thisArgument
+			}
+			thisInstance.Events[j] = thisEvent
+		}
+		output.Classes[i] = thisInstance
+	}
+	if output.ContentPrefixes == nil {
+		output.ContentPrefixes = []string{}
+	}
+	if output.OptimizedStrings == nil {
+		output.OptimizedStrings = []string{}
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(output)
+}
+
+// ParseSchemaJSON parses a network schema based on a JSON schema file
+// as produced by DumpJSON()
+func ParseSchemaJSON(schemafile io.Reader) (*NetworkSchema, error) {
+	input := &jsonNetworkSchema{}
+	schema := &NetworkSchema{}
+	err := json.NewDecoder(schemafile).Decode(input)
+	if err != nil {
+		return schema, err
+	}
+
+	schema.Enums = make([]*NetworkEnumSchema, len(input.Enums))
+	for i, enum := range input.Enums {
+		thisEnum := &NetworkEnumSchema{
+			Name:      enum.Name,
+			BitSize:   enum.BitSize,
+			NetworkID: uint16(i),
+		}
+		for _, item := range enum.Items {
+			thisEnum.Items = append(thisEnum.Items, &NetworkEnumItemSchema{
+				Name:  item.Name,
+				Value: item.Value,
+			})
+		}
+		schema.Enums[i] = thisEnum
+	}
+
+	schema.Instances = make([]*NetworkInstanceSchema, len(input.Classes))
+	for i, instance := range input.Classes {
+		thisInstance := &NetworkInstanceSchema{
+			Name:       instance.Name,
+			Unknown:    instance.Unknown,
+			Properties: make([]*NetworkPropertySchema, len(instance.Properties)),
+			Events:     make([]*NetworkEventSchema, len(instance.Events)),
+			NetworkID:  uint16(i),
+		}
+		for j, property := range instance.Properties {
+			thisProperty := &NetworkPropertySchema{
+				Name:           property.Name,
+				Type:           property.Type,
+				TypeString:     typeString(property.Type),
+				InstanceSchema: thisInstance,
+				NetworkID:      uint16(len(schema.Properties)),
+			}
+			thisProperty.EnumID, err = schema.resolveJSONEnumRef(property.Enum, property.EnumID)
+			if err != nil {
+				return schema, fmt.Errorf("property %s.%s: %s", instance.Name, property.Name, err.Error())
+			}
+			thisInstance.Properties[j] = thisProperty
+			schema.Properties = append(schema.Properties, thisProperty)
+		}
+		for j, event := range instance.Events {
+			thisEvent := &NetworkEventSchema{
+				Name:           event.Name,
+				Arguments:      make([]*NetworkArgumentSchema, len(event.Arguments)),
+				InstanceSchema: thisInstance,
+				NetworkID:      uint16(len(schema.Events)),
+			}
+			for k, argument := range event.Arguments {
+				thisArgument := &NetworkArgumentSchema{
+					Type:       argument.Type,
+					TypeString: typeString(argument.Type),
+				}
+				thisArgument.EnumID, err = schema.resolveJSONEnumRef(argument.Enum, argument.EnumID)
+				if err != nil {
+					return schema, fmt.Errorf("event %s.%s: %s", instance.Name, event.Name, err.Error())
+				}
+				thisEvent.Arguments[k] = thisArgument
+			}
+			thisInstance.Events[j] = thisEvent
+			schema.Events = append(schema.Events, thisEvent)
+		}
+		schema.Instances[i] = thisInstance
+	}
+
+	schema.ContentPrefixes = input.ContentPrefixes
+	schema.OptimizedStrings = input.OptimizedStrings
+
+	return schema, nil
+}
+
+// parseJSONSchemaOrAPIDump parses either a JSON network schema file or,
+// if the file is a Roblox API dump, generates a schema based on it
+// using typeMapping
+func parseJSONSchemaOrAPIDump(schemafile io.Reader, typeMapping map[string]uint8) (*NetworkSchema, []string, error) {
+	contents, err := ioutil.ReadAll(schemafile)
+	if err != nil {
+		return &NetworkSchema{}, nil, err
+	}
+	// Only API dumps have a version field
+	var probe struct {
+		Version *int
+	}
+	err = json.Unmarshal(contents, &probe)
+	if err != nil {
+		return &NetworkSchema{}, nil, err
+	}
+	if probe.Version == nil {
+		schema, err := ParseSchemaJSON(bytes.NewReader(contents))
+		return schema, nil, err
+	}
+
+	api, err := rbxapijson.Decode(bytes.NewReader(contents))
+	if err != nil {
+		return &NetworkSchema{}, nil, err
+	}
+	schema, warnings := SchemaFromAPIDump(api, typeMapping)
+	return schema, warnings, nil
+}
+
+// LoadSchema parses a network schema file in either the JSON format,
+// the legacy format understood by ParseSchema(), or a Roblox API dump
+// which will be passed to SchemaFromAPIDump(). The second return value
+// contains the warnings of SchemaFromAPIDump() and is empty for other formats.
+func LoadSchema(schemafile io.Reader) (*NetworkSchema, []string, error) {
+	return LoadSchemaWithMapping(schemafile, nil)
+}
+
+// LoadSchemaWithMapping works like LoadSchema, but API dumps are converted
+// using the given type mapping. It is ignored for other formats.
+// If it is nil, DefaultAPITypeMapping is used.
+func LoadSchemaWithMapping(schemafile io.Reader, typeMapping map[string]uint8) (*NetworkSchema, []string, error) {
+	file := bufio.NewReader(schemafile)
+	for {
+		char, _, err := file.ReadRune()
+		if err != nil {
+			return &NetworkSchema{}, nil, err
+		}
+		if unicode.IsSpace(char) {
+			continue
+		}
+		err = file.UnreadRune()
+		if err != nil {
+			return &NetworkSchema{}, nil, err
+		}
+		if char == '{' {
+			return parseJSONSchemaOrAPIDump(file, typeMapping)
+		}
+		schema, err := ParseSchema(file)
+		return schema, nil, err
+	}
+}
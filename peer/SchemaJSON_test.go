@@ -0,0 +1,122 @@
+package peer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testSchema = `2
+"NormalId" 3
+"Material" 6
+2 3 1
+"Part" 0
+	2
+	"Shape" 7 0
+	"Material" 7 1
+	0
+"RemoteEvent" 0
+	1
+	"Name" 1 0
+	1
+	"OnClientEvent" 2
+		29 0
+		7 1
+2
+"rbxasset://"
+"rbxassetid://"
+1
+"Humanoid"
+`
+
+func TestSchemaJSONRoundTrip(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatalf("failed to parse legacy schema: %s", err.Error())
+	}
+
+	var jsonSchema bytes.Buffer
+	err = schema.DumpJSON(&jsonSchema)
+	if err != nil {
+		t.Fatalf("failed to dump JSON schema: %s", err.Error())
+	}
+	if !strings.Contains(jsonSchema.String(), `"enum": "Material"`) {
+		t.Errorf("JSON schema doesn't reference enums by name:\n%s", jsonSchema.String())
+	}
+
+	newSchema, warnings, err := LoadSchema(&jsonSchema)
+	if err != nil {
+		t.Fatalf("failed to parse JSON schema: %s", err.Error())
+	}
+	if len(warnings) != 0 {
+		t.Errorf("JSON schema produced warnings: %v", warnings)
+	}
+
+	var legacySchema bytes.Buffer
+	err = newSchema.Dump(&legacySchema)
+	if err != nil {
+		t.Fatalf("failed to dump legacy schema: %s", err.Error())
+	}
+	if legacySchema.String() != testSchema {
+		t.Errorf("schema changed in round trip, got:\n%s\nexpected:\n%s", legacySchema.String(), testSchema)
+	}
+
+	event := newSchema.SchemaForClass("RemoteEvent").SchemaForEvent("OnClientEvent")
+	if event.NetworkID != 0 || event.Arguments[1].TypeString != "Enum" {
+		t.Errorf("event schema is incorrect: %+v", event.Arguments[1])
+	}
+}
+
+// duplicateEnumSchema has two enums with the same name and a property
+// with an unknown type
+const duplicateEnumSchema = `3
+"NormalId" 3
+"Material" 6
+"Material" 8
+1 3 0
+"Part" 0
+	3
+	"Material" 7 1
+	"NewMaterial" 7 2
+	"Future" 200 0
+	0
+0
+0
+`
+
+func TestSchemaJSONDuplicateEnums(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(duplicateEnumSchema))
+	if err != nil {
+		t.Fatalf("failed to parse legacy schema: %s", err.Error())
+	}
+	if typ := schema.SchemaForClass("Part").Properties[2].TypeString; typ != "Unknown 200" {
+		t.Errorf("legacy schema names unknown type %q", typ)
+	}
+
+	var jsonSchema bytes.Buffer
+	err = schema.DumpJSON(&jsonSchema)
+	if err != nil {
+		t.Fatalf("failed to dump JSON schema: %s", err.Error())
+	}
+	if strings.Contains(jsonSchema.String(), `"enum": "Material"`) {
+		t.Errorf("JSON schema references an ambiguous enum by name:\n%s", jsonSchema.String())
+	}
+	newSchema, err := ParseSchemaJSON(&jsonSchema)
+	if err != nil {
+		t.Fatalf("failed to parse JSON schema: %s", err.Error())
+	}
+	properties := newSchema.SchemaForClass("Part").Properties
+	if properties[0].EnumID != 1 || properties[1].EnumID != 2 {
+		t.Errorf("enum IDs changed in round trip: %d, %d", properties[0].EnumID, properties[1].EnumID)
+	}
+	if properties[2].TypeString != "Unknown 200" {
+		t.Errorf("JSON schema names unknown type %q", properties[2].TypeString)
+	}
+
+	ambiguous := `{"enums": [{"name": "Material", "bitSize": 6}, {"name": "Material", "bitSize": 8}],
+		"classes": [{"name": "Part", "properties": [{"name": "Material", "type": 7, "enum": "Material"}]}]}`
+	_, err = ParseSchemaJSON(strings.NewReader(ambiguous))
+	if err == nil {
+		t.Error("ambiguous enum name was resolved")
+	}
+}
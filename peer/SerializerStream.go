@@ -93,7 +93,7 @@ func (b *extendedWriter) WriteSerializedValue(val rbxfile.Value, writer PacketWr
 	case PropertyTypeLuauString:
 		err = b.writeLuauProtectedString(val.(datamodel.ValueSignedProtectedString), deferred)
 	case PropertyTypeInstance:
-		err = b.writeObject(val.(datamodel.ValueReference).Instance, writer.Context())
+		err = b.writeReference(val.(datamodel.ValueReference), writer.Context())
 	case PropertyTypeContent:
 		err = b.writeNewContent(val.(rbxfile.ValueContent), writer.Context())
 	case PropertyTypeTuple:
@@ -107,7 +107,7 @@ func (b *extendedWriter) WriteSerializedValue(val rbxfile.Value, writer PacketWr
 	case PropertyTypeOptimizedString:
 		err = b.writeOptimizedString(val.(rbxfile.ValueString), writer.Context())
 	default:
-		return b.writeSerializedValueGeneric(val, valueType, deferred)
+		return b.writeSerializedValueGeneric(val, writer, valueType, deferred)
 	}
 	return err
 }
@@ -211,13 +211,13 @@ func (b *joinSerializeWriter) WriteSerializedValue(val rbxfile.Value, writer Pac
 	case PropertyTypeLuauString:
 		err = b.writeLuauProtectedString(val.(datamodel.ValueSignedProtectedString), deferred)
 	case PropertyTypeInstance:
-		err = b.writeObject(val.(datamodel.ValueReference).Instance, writer.Context())
+		err = b.writeReference(val.(datamodel.ValueReference), writer.Context())
 	case PropertyTypeContent:
 		err = b.writeNewContent(val.(rbxfile.ValueContent))
 	case PropertyTypeOptimizedString:
 		err = b.writeOptimizedString(val.(rbxfile.ValueString), writer.Context())
 	default:
-		return b.writeSerializedValueGeneric(val, valueType, deferred)
+		return b.writeSerializedValueGeneric(val, writer, valueType, deferred)
 	}
 	return err
 }
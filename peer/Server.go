@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"sync"
 
 	"github.com/Gskartwii/roblox-dissector/datamodel"
 	"github.com/olebedev/emitter"
@@ -23,19 +24,37 @@ type ServerClient struct {
 	// Among other things, it is used in the determining the player's name
 	// (i.e. Player1, Player2, etc.)
 	Index int
+	// PlayerID is the player ID sent by the client in ID_SUBMIT_TICKET.
+	// HasPlayerID is false if the ticket hasn't been received yet.
+	PlayerID    int64
+	HasPlayerID bool
 
 	replicatedInstances []*ReplicationContainer
 	handlingChild       *datamodel.Instance
 	handlingProp        handledChange
 	handlingEvent       handledChange
 	handlingRemoval     *datamodel.Instance
+
+	// streaming is nil if Workspace isn't streamed to the client
+	streaming *streamingState
+
+	remoteMutex        sync.Mutex
+	invocationIndex    int32
+	pendingInvocations map[int32]chan remoteResult
+
+	challengeMutex     sync.Mutex
+	idChallenge        uint32
+	idChallengePending bool
 }
 
 // CustomServer is custom implementation of a Roblox server
 type CustomServer struct {
-	Context            *CommunicationContext
-	Connection         *net.UDPConn
-	Clients            map[string]*ServerClient
+	Context    *CommunicationContext
+	Connection *net.UDPConn
+	Clients    map[string]*ServerClient
+	// ClientEmitter emits "client" with the *ServerClient when a client
+	// connects, and "rejected" with the *net.UDPAddr and the reason when
+	// a connection is ignored
 	ClientEmitter      *emitter.Emitter
 	Address            *net.UDPAddr
 	GUID               uint64
@@ -43,7 +62,23 @@ type CustomServer struct {
 	InstanceDictionary *datamodel.InstanceDictionary
 	RunningContext     context.Context
 
+	// Config describes the behavior of the server. It should
+	// be modified before Start() is called.
+	Config *ServerConfig
+	// Bans are checked when clients connect and when
+	// they submit their ticket
+	Bans *BanList
+
 	PlayerIndex int
+
+	clientsMutex   sync.RWMutex
+	physicsMutex   sync.Mutex
+	networkOwners  map[*datamodel.Instance]*ServerClient
+	pendingPhysics map[*datamodel.Instance]*pendingPhysics
+
+	remoteMutex     sync.RWMutex
+	remoteEvents    map[*datamodel.Instance][]RemoteEventHandler
+	remoteFunctions map[*datamodel.Instance]RemoteFunctionHandler
 }
 
 // ReadPacket processes a UDP packet sent by the client
@@ -102,6 +137,8 @@ func newServerClient(clientAddr *net.UDPAddr, server *CustomServer, context *Com
 		Server:             server,
 		Address:            clientAddr,
 		Index:              server.PlayerIndex,
+
+		pendingInvocations: make(map[int32]chan remoteResult),
 	}
 
 	return newClient
@@ -111,18 +148,45 @@ func (myServer *CustomServer) bindToDisconnection(client *ServerClient) {
 	// HACK: gets priority in the emitter via Use()
 	client.GenericEvents.Use("disconnected", func(e *emitter.Event) {
 		println("server received client disconnection")
+		myServer.clientsMutex.Lock()
 		delete(myServer.Clients, client.Address.String())
+		myServer.clientsMutex.Unlock()
+		myServer.releaseNetworkOwnership(client)
+		client.removePlayer()
 	})
 }
 
-// Start starts the server's read loop
+// clientList returns a snapshot of the connected clients
+func (myServer *CustomServer) clientList() []*ServerClient {
+	myServer.clientsMutex.RLock()
+	defer myServer.clientsMutex.RUnlock()
+	clients := make([]*ServerClient, 0, len(myServer.Clients))
+	for _, client := range myServer.Clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func (myServer *CustomServer) clientCount() int {
+	myServer.clientsMutex.RLock()
+	defer myServer.clientsMutex.RUnlock()
+	return len(myServer.Clients)
+}
+
+// Start starts the server's read loop. An error is returned
+// immediately if Config contains invalid settings.
 func (myServer *CustomServer) Start() error {
+	err := myServer.Config.validate()
+	if err != nil {
+		return err
+	}
 	conn, err := net.ListenUDP("udp", myServer.Address)
 	if err != nil {
 		return err
 	}
 	myServer.Connection = conn
 	defer myServer.stop()
+	myServer.startPhysicsBroadcast()
 
 	buf := make([]byte, 1492)
 	for {
@@ -137,15 +201,27 @@ func (myServer *CustomServer) Start() error {
 		default:
 		}
 
+		myServer.clientsMutex.RLock()
 		thisClient, ok := myServer.Clients[client.String()]
+		myServer.clientsMutex.RUnlock()
 		if !ok {
 			// always check for offline messages, disconnected peers
 			// may keep sending packets which must be ignored
 			if !IsOfflineMessage(buf[:n]) {
 				continue
 			}
+			if reason, banned := myServer.Bans.IsAddressBanned(client.IP); banned {
+				<-myServer.ClientEmitter.Emit("rejected", client, "address is banned: "+reason)
+				continue
+			}
+			if myServer.Config.MaxPlayers != 0 && myServer.clientCount() >= myServer.Config.MaxPlayers {
+				<-myServer.ClientEmitter.Emit("rejected", client, "server is full")
+				continue
+			}
 			thisClient = newServerClient(client, myServer, myServer.Context)
+			myServer.clientsMutex.Lock()
 			myServer.Clients[client.String()] = thisClient
+			myServer.clientsMutex.Unlock()
 
 			myServer.bindToDisconnection(thisClient)
 
@@ -158,7 +234,7 @@ func (myServer *CustomServer) Start() error {
 }
 
 func (myServer *CustomServer) stop() {
-	for _, client := range myServer.Clients {
+	for _, client := range myServer.clientList() {
 		client.Disconnect()
 	}
 	myServer.Connection.Close()
@@ -166,7 +242,14 @@ func (myServer *CustomServer) stop() {
 
 // NewCustomServer initializes a CustomServer
 func NewCustomServer(ctx context.Context, port uint16, schema *NetworkSchema, dataModel *datamodel.DataModel, dict *datamodel.InstanceDictionary) (*CustomServer, error) {
-	server := &CustomServer{Clients: make(map[string]*ServerClient)}
+	server := &CustomServer{
+		Clients:        make(map[string]*ServerClient),
+		networkOwners:  make(map[*datamodel.Instance]*ServerClient),
+		pendingPhysics: make(map[*datamodel.Instance]*pendingPhysics),
+
+		remoteEvents:    make(map[*datamodel.Instance][]RemoteEventHandler),
+		remoteFunctions: make(map[*datamodel.Instance]RemoteFunctionHandler),
+	}
 
 	var err error
 	server.Address, err = net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", port))
@@ -184,6 +267,8 @@ func NewCustomServer(ctx context.Context, port uint16, schema *NetworkSchema, da
 	server.Context.InstanceTopScope = server.InstanceDictionary.Scope
 	server.Context.ServerPeerID = server.InstanceDictionary.PeerID
 	server.ClientEmitter = emitter.New(0)
+	server.Config = DefaultServerConfig()
+	server.Bans = NewBanList()
 
 	return server, nil
 }
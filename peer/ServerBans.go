@@ -0,0 +1,116 @@
+package peer
+
+import (
+	"net"
+	"sync"
+)
+
+// BanList contains the addresses and player IDs that aren't allowed
+// to join a CustomServer. Each ban has a reason that is shown to the
+// player when they are kicked.
+type BanList struct {
+	mutex     sync.RWMutex
+	addresses map[string]string
+	playerIDs map[int64]string
+}
+
+// NewBanList returns an empty BanList
+func NewBanList() *BanList {
+	return &BanList{
+		addresses: make(map[string]string),
+		playerIDs: make(map[int64]string),
+	}
+}
+
+// BanAddress bans the IP address. All ports are banned.
+func (bans *BanList) BanAddress(ip net.IP, reason string) {
+	bans.mutex.Lock()
+	bans.addresses[ip.String()] = reason
+	bans.mutex.Unlock()
+}
+
+// UnbanAddress removes the ban from the IP address
+func (bans *BanList) UnbanAddress(ip net.IP) {
+	bans.mutex.Lock()
+	delete(bans.addresses, ip.String())
+	bans.mutex.Unlock()
+}
+
+// BanPlayerID bans the player ID sent in ID_SUBMIT_TICKET
+func (bans *BanList) BanPlayerID(playerID int64, reason string) {
+	bans.mutex.Lock()
+	bans.playerIDs[playerID] = reason
+	bans.mutex.Unlock()
+}
+
+// UnbanPlayerID removes the ban from the player ID
+func (bans *BanList) UnbanPlayerID(playerID int64) {
+	bans.mutex.Lock()
+	delete(bans.playerIDs, playerID)
+	bans.mutex.Unlock()
+}
+
+// IsAddressBanned returns the ban reason for the IP address and whether it is banned
+func (bans *BanList) IsAddressBanned(ip net.IP) (string, bool) {
+	bans.mutex.RLock()
+	defer bans.mutex.RUnlock()
+	reason, ok := bans.addresses[ip.String()]
+	return reason, ok
+}
+
+// IsPlayerIDBanned returns the ban reason for the player ID and whether it is banned
+func (bans *BanList) IsPlayerIDBanned(playerID int64) (string, bool) {
+	bans.mutex.RLock()
+	defer bans.mutex.RUnlock()
+	reason, ok := bans.playerIDs[playerID]
+	return reason, ok
+}
+
+// Disconnect sends a "-1" disconnection reason packet to the client.
+// Unlike PacketLogicHandler.Disconnect(), it doesn't close the server's
+// connection, which is shared by all clients.
+func (client *ServerClient) Disconnect() {
+	client.DisconnectWithReason(-1)
+}
+
+// DisconnectWithReason is like Disconnect(), but allows specifying
+// the disconnection reason
+func (client *ServerClient) DisconnectWithReason(reason int32) {
+	if client.notifyDisconnection(reason) {
+		client.cleanup()
+	}
+}
+
+// Kick displays the message to the client and disconnects it
+func (client *ServerClient) Kick(message string) error {
+	err := client.WritePacket(&Packet98Layer{Message: message})
+	client.Disconnect()
+	return err
+}
+
+// Ban bans the client's address and player ID and kicks it
+func (myServer *CustomServer) Ban(client *ServerClient, reason string) error {
+	myServer.Bans.BanAddress(client.Address.IP, reason)
+	if client.HasPlayerID {
+		myServer.Bans.BanPlayerID(client.PlayerID, reason)
+	}
+	return client.Kick(reason)
+}
+
+// checkPlayerBan records the client's player ID and kicks the client if it
+// is banned. It returns false if the client was kicked.
+func (client *ServerClient) checkPlayerBan(ticket *Packet8ALayer) bool {
+	client.PlayerID = ticket.PlayerID
+	client.HasPlayerID = true
+
+	reason, banned := client.Server.Bans.IsPlayerIDBanned(ticket.PlayerID)
+	if !banned {
+		return true
+	}
+	println("kicking banned player", ticket.PlayerID)
+	err := client.Kick(reason)
+	if err != nil {
+		println("kick error: ", err.Error())
+	}
+	return false
+}
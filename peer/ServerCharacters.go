@@ -0,0 +1,242 @@
+package peer
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/olebedev/emitter"
+	"github.com/robloxapi/rbxfile"
+)
+
+// defaultSpawnPosition is used if Workspace doesn't contain any SpawnLocations
+var defaultSpawnPosition = rbxfile.ValueVector3{X: 0, Y: 10, Z: 0}
+
+// addServerInstance assigns new references to the instance and its descendants
+// and registers them so that clients can refer to them
+func (myServer *CustomServer) addServerInstance(inst *datamodel.Instance) {
+	inst.Ref = myServer.InstanceDictionary.NewReference()
+	myServer.Context.InstancesByReference.AddInstance(inst.Ref, inst)
+	for _, child := range inst.Children {
+		myServer.addServerInstance(child)
+	}
+}
+
+func (client *ServerClient) createPlayer() error {
+	player, _ := datamodel.NewInstance("Player", nil)
+	player.Set("Name", rbxfile.ValueString(fmt.Sprintf("Player%d", client.Index)))
+	player.Set("AccountAgeReplicate", rbxfile.ValueInt(117))
+	player.Set("CharacterAppearanceId", rbxfile.ValueInt64(1))
+	player.Set("ChatPrivacyMode", datamodel.ValueToken{Value: 0})
+	player.Set("ReplicatedLocaleId", rbxfile.ValueString("en-us"))
+	player.Set("UserId", rbxfile.ValueInt64(-client.Index))
+	player.Set("userId", rbxfile.ValueInt64(-client.Index))
+	player.Set("Character", datamodel.ValueReference{Reference: datamodel.NullReference})
+
+	playerGui, _ := datamodel.NewInstance("PlayerGui", nil)
+	err := player.AddChild(playerGui)
+	if err != nil {
+		return err
+	}
+	client.Server.addServerInstance(player)
+
+	client.Player = player
+	return client.DataModel.FindService("Players").AddChild(player)
+}
+
+// cloneInstance copies the instance and its descendants. References
+// between the copied instances are updated to point to the copies.
+func cloneInstance(inst *datamodel.Instance) *datamodel.Instance {
+	clones := make(map[*datamodel.Instance]*datamodel.Instance)
+	clone := cloneTree(inst, clones)
+	for _, copied := range clones {
+		for name, value := range copied.Properties {
+			ref, ok := value.(datamodel.ValueReference)
+			if !ok || ref.Instance == nil {
+				continue
+			}
+			if target, ok := clones[ref.Instance]; ok {
+				copied.Properties[name] = datamodel.ValueReference{Instance: target}
+			}
+		}
+	}
+	return clone
+}
+
+func cloneTree(inst *datamodel.Instance, clones map[*datamodel.Instance]*datamodel.Instance) *datamodel.Instance {
+	clone, _ := datamodel.NewInstance(inst.ClassName, nil)
+	clones[inst] = clone
+	inst.PropertiesMutex.RLock()
+	for name, value := range inst.Properties {
+		clone.Properties[name] = value.Copy()
+	}
+	inst.PropertiesMutex.RUnlock()
+	for _, child := range inst.Children {
+		clone.AddChild(cloneTree(child, clones))
+	}
+	return clone
+}
+
+// fixReferences updates the Reference fields of reference properties
+// after the instances have been assigned their references
+func fixReferences(inst *datamodel.Instance) {
+	for name, value := range inst.Properties {
+		ref, ok := value.(datamodel.ValueReference)
+		if ok && ref.Instance != nil {
+			inst.Properties[name] = datamodel.ValueReference{Instance: ref.Instance, Reference: ref.Instance.Ref}
+		}
+	}
+	for _, child := range inst.Children {
+		fixReferences(child)
+	}
+}
+
+func newPart(name string, size rbxfile.ValueVector3, position rbxfile.ValueVector3) *datamodel.Instance {
+	part, _ := datamodel.NewInstance("Part", nil)
+	part.Properties["Name"] = rbxfile.ValueString(name)
+	part.Properties["Size"] = size
+	part.Properties["CFrame"] = rbxfile.ValueCFrame{
+		Position: position,
+		Rotation: [9]float32{1, 0, 0, 0, 1, 0, 0, 0, 1},
+	}
+	part.Properties["Anchored"] = rbxfile.ValueBool(false)
+	part.Properties["CanCollide"] = rbxfile.ValueBool(true)
+	return part
+}
+
+// defaultCharacter builds a minimal character for places
+// that don't have a character template
+func defaultCharacter() *datamodel.Instance {
+	character, _ := datamodel.NewInstance("Model", nil)
+	rootPart := newPart("HumanoidRootPart", rbxfile.ValueVector3{X: 2, Y: 2, Z: 1}, rbxfile.ValueVector3{})
+	rootPart.Properties["Transparency"] = rbxfile.ValueFloat(1)
+	character.AddChild(rootPart)
+	character.AddChild(newPart("Head", rbxfile.ValueVector3{X: 2, Y: 1, Z: 1}, rbxfile.ValueVector3{Y: 1.5}))
+	humanoid, _ := datamodel.NewInstance("Humanoid", nil)
+	humanoid.Properties["Name"] = rbxfile.ValueString("Humanoid")
+	character.AddChild(humanoid)
+	character.Properties["PrimaryPart"] = datamodel.ValueReference{Instance: rootPart}
+	return character
+}
+
+func findSpawnLocations(inst *datamodel.Instance, spawns []*datamodel.Instance) []*datamodel.Instance {
+	for _, child := range inst.Children {
+		if child.ClassName == "SpawnLocation" {
+			spawns = append(spawns, child)
+		}
+		spawns = findSpawnLocations(child, spawns)
+	}
+	return spawns
+}
+
+// spawnPosition picks a random SpawnLocation in Workspace and returns
+// the position above it
+func spawnPosition(workspace *datamodel.Instance) rbxfile.ValueVector3 {
+	spawns := findSpawnLocations(workspace, nil)
+	if len(spawns) == 0 {
+		return defaultSpawnPosition
+	}
+	spawn := spawns[rand.Intn(len(spawns))]
+	cframe, _ := spawn.Get("CFrame").(rbxfile.ValueCFrame)
+	size, _ := spawn.Get("Size").(rbxfile.ValueVector3)
+	position := cframe.Position
+	position.Y += size.Y/2 + 3
+	return position
+}
+
+// moveInstance translates the CFrames of the instance and its descendants
+func moveInstance(inst *datamodel.Instance, offset rbxfile.ValueVector3) {
+	if cframe, ok := inst.Properties["CFrame"].(rbxfile.ValueCFrame); ok {
+		cframe.Position.X += offset.X
+		cframe.Position.Y += offset.Y
+		cframe.Position.Z += offset.Z
+		inst.Properties["CFrame"] = cframe
+	}
+	for _, child := range inst.Children {
+		moveInstance(child, offset)
+	}
+}
+
+// Character returns the client's current character, or nil if it
+// hasn't been spawned
+func (client *ServerClient) Character() *datamodel.Instance {
+	if client.Player == nil {
+		return nil
+	}
+	character, _ := client.Player.Get("Character").(datamodel.ValueReference)
+	return character.Instance
+}
+
+// LoadCharacter spawns a new character for the client, removing the old one.
+// The character is cloned from Config.CharacterTemplate. If the template
+// doesn't exist, a minimal character is created.
+func (client *ServerClient) LoadCharacter() error {
+	if client.Player == nil {
+		return errors.New("player hasn't been created")
+	}
+	server := client.Server
+	dataModel := client.DataModel
+	workspace := dataModel.FindService("Workspace")
+	if workspace == nil {
+		return errors.New("no Workspace")
+	}
+
+	oldCharacter := client.Character()
+	if oldCharacter != nil {
+		server.Context.removeInstance(oldCharacter)
+	}
+
+	var character *datamodel.Instance
+	template := dataModel.FindInstance(server.Config.CharacterTemplate)
+	if template != nil {
+		character = cloneInstance(template)
+	} else {
+		character = defaultCharacter()
+	}
+	character.Properties["Name"] = rbxfile.ValueString(client.Player.Name())
+	if position, ok := instancePosition(character); ok {
+		target := spawnPosition(workspace)
+		moveInstance(character, rbxfile.ValueVector3{
+			X: target.X - position.X,
+			Y: target.Y - position.Y,
+			Z: target.Z - position.Z,
+		})
+	}
+	server.addServerInstance(character)
+	fixReferences(character)
+
+	// The client simulates its own character
+	server.SetNetworkOwner(character, client)
+	err := workspace.AddChild(character)
+	if err != nil {
+		return err
+	}
+	client.Player.Set("Character", datamodel.ValueReference{
+		Reference: character.Ref,
+		Instance:  character,
+	})
+	return nil
+}
+
+func (client *ServerClient) requestCharHandler(e *emitter.Event) {
+	if !client.Server.Config.CharacterAutoSpawn || client.Character() != nil {
+		return
+	}
+	err := client.LoadCharacter()
+	if err != nil {
+		println("character error: ", err.Error())
+	}
+}
+
+// removePlayer removes the client's Player and character from the DataModel
+func (client *ServerClient) removePlayer() {
+	if client.Player == nil {
+		return
+	}
+	server := client.Server
+	if character := client.Character(); character != nil {
+		server.Context.removeInstance(character)
+	}
+	server.Context.removeInstance(client.Player)
+}
@@ -0,0 +1,91 @@
+package peer
+
+import (
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/olebedev/emitter"
+	"github.com/robloxapi/rbxfile"
+)
+
+// ChatFilter is called for every chat message sent by a client.
+// It returns the message that should be relayed to the other clients,
+// or false if the message should be dropped.
+type ChatFilter func(client *ServerClient, message string) (string, bool)
+
+// SendSystemMessage displays a system message in the client's chat
+func (client *ServerClient) SendSystemMessage(message string) error {
+	return client.WritePacket(&Packet8BLayer{Message: message})
+}
+
+// BroadcastSystemMessage displays a system message in the chat of every client
+func (myServer *CustomServer) BroadcastSystemMessage(message string) {
+	myServer.relayChat(&Packet8BLayer{Message: message}, func(*ServerClient) bool {
+		return true
+	})
+}
+
+func (myServer *CustomServer) relayChat(packet RakNetPacket, shouldReceive func(*ServerClient) bool) {
+	for _, client := range myServer.clientList() {
+		if client.Player == nil || !shouldReceive(client) {
+			continue
+		}
+		err := client.WritePacket(packet)
+		if err != nil {
+			println("chat error: ", err.Error())
+		}
+	}
+}
+
+func sameTeam(a *datamodel.Instance, b *datamodel.Instance) bool {
+	if neutral, ok := a.Get("Neutral").(rbxfile.ValueBool); ok && bool(neutral) {
+		return false
+	}
+	if neutral, ok := b.Get("Neutral").(rbxfile.ValueBool); ok && bool(neutral) {
+		return false
+	}
+	teamColor := a.Get("TeamColor")
+	return teamColor != nil && teamColor == b.Get("TeamColor")
+}
+
+// filterChat checks that the client is chatting as its own player and
+// runs the message through the server's ChatFilter
+func (client *ServerClient) filterChat(sender *datamodel.Instance, message string) (string, bool) {
+	if client.Player == nil || sender != client.Player {
+		println("dropping chat from", client.Address.String(), "for another player")
+		return "", false
+	}
+	filter := client.Server.Config.ChatFilter
+	if filter == nil {
+		return message, true
+	}
+	return filter(client, message)
+}
+
+func (client *ServerClient) chatHandler(e *emitter.Event) {
+	server := client.Server
+	switch packet := e.Args[0].(type) {
+	case *Packet87Layer:
+		message, ok := client.filterChat(packet.Instance, packet.Message)
+		if !ok {
+			return
+		}
+		server.relayChat(&Packet87Layer{Instance: client.Player, Message: message}, func(*ServerClient) bool {
+			return true
+		})
+	case *Packet88Layer:
+		message, ok := client.filterChat(packet.Instance, packet.Message)
+		if !ok {
+			return
+		}
+		server.relayChat(&Packet88Layer{Instance: client.Player, Message: message}, func(receiver *ServerClient) bool {
+			return receiver == client || sameTeam(client.Player, receiver.Player)
+		})
+	case *Packet8CLayer:
+		message, ok := client.filterChat(packet.Instance, packet.Message)
+		if !ok || packet.Recipient == nil {
+			return
+		}
+		server.relayChat(&Packet8CLayer{Instance: client.Player, Recipient: packet.Recipient, Message: message}, func(receiver *ServerClient) bool {
+			return receiver == client || receiver.Player == packet.Recipient
+		})
+	}
+}
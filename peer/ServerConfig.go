@@ -0,0 +1,152 @@
+package peer
+
+import (
+	"errors"
+	"time"
+)
+
+// JoinDataConfig describes how a service is replicated to clients
+type JoinDataConfig struct {
+	ClassName           string
+	ReplicateProperties bool
+	ReplicateChildren   bool
+}
+
+// DefaultJoinDataConfiguration is the list of services replicated by
+// CustomServer by default
+var DefaultJoinDataConfiguration = []JoinDataConfig{
+	{"ReplicatedFirst", true, true},
+	{"Lighting", true, true},
+	{"SoundService", true, true},
+	{"TeleportService", true, false},
+	{"StarterPack", false, true},
+	{"StarterGui", true, true},
+	{"StarterPlayer", true, true},
+	{"CSGDictionaryService", false, true},
+	{"Workspace", true, true},
+	{"JointsService", false, true},
+	{"Players", true, true},
+	{"Teams", false, true},
+	{"InsertService", true, true},
+	{"Chat", true, true},
+	{"LocalizationService", true, true},
+	{"FriendService", true, true},
+	{"MarketplaceService", true, true},
+	{"BadgeService", true, false},
+	{"ReplicatedStorage", true, true},
+	{"RobloxReplicatedStorage", true, true},
+	{"TestService", true, true},
+	{"LogService", true, false},
+	{"PointsService", true, false},
+	{"AdService", true, false},
+	{"SocialService", true, false},
+}
+
+// DefaultServerParams contains the ID_DICTIONARY_FORMAT flags that are
+// enabled by CustomServer by default
+var DefaultServerParams = map[string]bool{
+	"FixDictionaryScopePlatformsReplication":              true,
+	"ReplicateInterpolateRelativeHumanoidPlatformsMotion": true,
+	"FixRaysInWedges":       true,
+	"FixBallRaycasts":       true,
+	"UseNativePathWaypoint": true,
+	"PgsForAll":             true,
+}
+
+// ServerConfig describes the behavior of a CustomServer
+type ServerConfig struct {
+	// JoinData lists the services that will be replicated to clients,
+	// in order, along with the rules for replicating them
+	JoinData []JoinDataConfig
+	// Params contains the values of the flags sent to clients in ID_DICTIONARY_FORMAT
+	// Flags that are requested by a client but missing from Params are false.
+	Params map[string]bool
+	// Capabilities are the capabilities sent to clients in ID_OPEN_CONNECTION_REPLY_2
+	Capabilities uint64
+
+	// FilteringEnabled and CharacterAutoSpawn are sent to clients in ID_SET_GLOBALS
+	FilteringEnabled   bool
+	CharacterAutoSpawn bool
+	// CharacterTemplate is the path of the model that characters are cloned from,
+	// as accepted by DataModel.FindInstance(). If the model doesn't exist,
+	// a minimal character is created instead.
+	CharacterTemplate string
+
+	// StreamingEnabled enables streaming of Workspace. Clients that set
+	// CapabilityDebugForceStreamingEnabled use streaming regardless of this setting.
+	StreamingEnabled bool
+	// StreamingRegionSize is the size of a streaming region in studs
+	StreamingRegionSize float32
+	// StreamingTargetRadius is the distance in studs from the character
+	// within which Workspace children are streamed in
+	StreamingTargetRadius float32
+	// StreamingUpdateInterval is how often the streamed regions are updated
+	StreamingUpdateInterval time.Duration
+
+	// PhysicsBroadcastInterval is how often physics updates received from
+	// network owners are sent to the other clients. If it is 0, physics
+	// aren't broadcast.
+	PhysicsBroadcastInterval time.Duration
+
+	// Permission decides which changes requested by clients are applied
+	// and replicated to other clients. If it is nil, DefaultReplicationPermission is used.
+	Permission ReplicationPermission
+
+	// ChatFilter is called for chat messages sent by clients before they
+	// are relayed. If it is nil, messages are relayed as-is.
+	ChatFilter ChatFilter
+
+	// Security is used to verify the hashes in ID_SUBMIT_TICKET and the responses
+	// to ID challenges. If it is nil, clients aren't verified.
+	Security SecurityHandler
+	// SecurityKeys are the accepted security keys. If it is empty, any key is accepted.
+	// Security keys are only checked if Security is set.
+	SecurityKeys []string
+	// IDChallengeTimeout is how long clients have to respond to the ID challenge.
+	// If it is 0, clients may respond at any time.
+	IDChallengeTimeout time.Duration
+	// TicketValidator is called for every submitted ticket, regardless of Security.
+	// If it is nil, all tickets are accepted.
+	TicketValidator TicketValidator
+
+	// MaxPlayers is the maximum number of clients that can be connected
+	// at the same time. If it is 0, the number of clients isn't limited.
+	MaxPlayers int
+}
+
+// validate returns an error if the settings can't be used by CustomServer
+func (config *ServerConfig) validate() error {
+	// Clients may force streaming, so the streaming settings
+	// are checked even if StreamingEnabled is false
+	if config.StreamingRegionSize <= 0 {
+		return errors.New("StreamingRegionSize must be positive")
+	}
+	if config.StreamingUpdateInterval <= 0 {
+		return errors.New("StreamingUpdateInterval must be positive")
+	}
+	return nil
+}
+
+// DefaultServerConfig returns a new ServerConfig containing the default settings
+func DefaultServerConfig() *ServerConfig {
+	config := &ServerConfig{
+		JoinData:           append([]JoinDataConfig(nil), DefaultJoinDataConfiguration...),
+		Params:             make(map[string]bool, len(DefaultServerParams)),
+		Capabilities:       DefaultCapabilities,
+		FilteringEnabled:   true,
+		CharacterAutoSpawn: true,
+		CharacterTemplate:  "StarterPlayer.StarterCharacter",
+
+		StreamingRegionSize:     64,
+		StreamingTargetRadius:   256,
+		StreamingUpdateInterval: time.Second,
+
+		PhysicsBroadcastInterval: time.Second / 20,
+
+		IDChallengeTimeout: 30 * time.Second,
+	}
+	for name, value := range DefaultServerParams {
+		config.Params[name] = value
+	}
+	return config
+}
@@ -1,7 +1,6 @@
 package peer
 
 import (
-	"fmt"
 	"net"
 	"time"
 
@@ -11,40 +10,6 @@ import (
 	"github.com/robloxapi/rbxfile"
 )
 
-type joinDataConfig struct {
-	ClassName           string
-	ReplicateProperties bool
-	ReplicateChildren   bool
-}
-
-var joinDataConfiguration = []joinDataConfig{
-	{"ReplicatedFirst", true, true},
-	{"Lighting", true, true},
-	{"SoundService", true, true},
-	{"TeleportService", true, false},
-	{"StarterPack", false, true},
-	{"StarterGui", true, true},
-	{"StarterPlayer", true, true},
-	{"CSGDictionaryService", false, true},
-	{"Workspace", true, true},
-	{"JointsService", false, true},
-	{"Players", true, true},
-	{"Teams", false, true},
-	{"InsertService", true, true},
-	{"Chat", true, true},
-	{"LocalizationService", true, true},
-	{"FriendService", true, true},
-	{"MarketplaceService", true, true},
-	{"BadgeService", true, false},
-	{"ReplicatedStorage", true, true},
-	{"RobloxReplicatedStorage", true, true},
-	{"TestService", true, true},
-	{"LogService", true, false},
-	{"PointsService", true, false},
-	{"AdService", true, false},
-	{"SocialService", true, false},
-}
-
 func (client *ServerClient) offline5Handler(e *emitter.Event) {
 	println("Received connection!", client.Address.String())
 	client.WriteOffline(&Packet06Layer{
@@ -55,11 +20,17 @@ func (client *ServerClient) offline5Handler(e *emitter.Event) {
 }
 func (client *ServerClient) offline7Handler(e *emitter.Event) {
 	println("Received reply 7!", client.Address.String())
+	// Use the older protocol version if the client is outdated
+	version := e.Args[0].(*Packet07Layer).SupportedVersion
+	if version == 0 || version > CurrentProtocolVersion {
+		version = CurrentProtocolVersion
+	}
 	client.WriteOffline(&Packet08Layer{
-		GUID:         client.Server.GUID,
-		IPAddress:    client.Address,
-		MTU:          1492,
-		Capabilities: CapabilityServerCopiesPlayerGui3 | CapabilityIHasMinDistToUnstreamed | CapabilityReplicateLuau | CapabilityPositionBasedStreaming | CapabilityVersionedIDSync | CapabilitySystemAddressIsPeerId | CapabilityStreamingPrefetch | CapabilityUseBlake2BHashInSharedString | 0xDC000,
+		GUID:             client.Server.GUID,
+		IPAddress:        client.Address,
+		MTU:              1492,
+		SupportedVersion: version,
+		Capabilities:     client.Server.Config.Capabilities,
 	})
 }
 func (client *ServerClient) connectionRequestHandler(e *emitter.Event) {
@@ -89,17 +60,7 @@ func (client *ServerClient) requestParamsHandler(e *emitter.Event) {
 	params := make(map[string]bool)
 
 	for _, flag := range e.Args[0].(*Packet90Layer).RequestedFlags {
-		value := false
-		switch flag {
-		case "FixDictionaryScopePlatformsReplication",
-			"ReplicateInterpolateRelativeHumanoidPlatformsMotion",
-			"FixRaysInWedges",
-			"FixBallRaycasts",
-			"UseNativePathWaypoint",
-			"PgsForAll":
-			value = true
-		}
-		params[flag] = value
+		params[flag] = client.Server.Config.Params[flag]
 	}
 
 	client.WritePacket(&Packet93Layer{
@@ -110,6 +71,13 @@ func (client *ServerClient) requestParamsHandler(e *emitter.Event) {
 }
 
 func (client *ServerClient) topReplicate() error {
+	config := client.Server.Config
+	if client.usesStreaming() {
+		client.streaming = &streamingState{
+			units: make(map[*datamodel.Instance]StreamInfo),
+		}
+	}
+	joinDataConfiguration := config.JoinData
 	topReplicationItems := make([]*Packet81LayerItem, 0, len(joinDataConfiguration))
 	for _, instance := range joinDataConfiguration {
 		service := client.Context.DataModel.FindService(instance.ClassName)
@@ -136,12 +104,12 @@ func (client *ServerClient) topReplicate() error {
 	}
 
 	return client.WritePacket(&Packet81Layer{
-		StreamJob:          false,
-		FilteringEnabled:   true,
+		StreamJob:          client.StreamingEnabled(),
+		FilteringEnabled:   config.FilteringEnabled,
 		Bool1:              true,
 		Bool2:              true,
 		Bool3:              true,
-		CharacterAutoSpawn: true,
+		CharacterAutoSpawn: config.CharacterAutoSpawn,
 		Items:              topReplicationItems,
 	})
 }
@@ -155,43 +123,11 @@ func (client *ServerClient) createCameraScript(parent *datamodel.Instance) error
 	return parent.AddChild(cameraScript)
 }
 
-func (client *ServerClient) createPlayer() error {
-	player, _ := datamodel.NewInstance("Player", nil)
-	player.Set("Name", rbxfile.ValueString(fmt.Sprintf("Player%d", client.Index)))
-	player.Set("AccountAgeReplicate", rbxfile.ValueInt(117))
-	player.Set("CharacterAppearanceId", rbxfile.ValueInt64(1))
-	player.Set("ChatPrivacyMode", datamodel.ValueToken{Value: 0})
-	player.Set("ReplicatedLocaleId", rbxfile.ValueString("en-us"))
-	player.Set("UserId", rbxfile.ValueInt64(-client.Index))
-	player.Set("userId", rbxfile.ValueInt64(-client.Index))
-	player.Ref = client.Server.InstanceDictionary.NewReference()
-
-	client.Player = player
-
-	hum, _ := datamodel.NewInstance("Model", nil)
-	hum.Set("Name", player.Get("Name"))
-	hum.Ref = client.Server.InstanceDictionary.NewReference()
-	err := client.DataModel.FindService("Workspace").AddChild(hum)
-	if err != nil {
-		return err
-	}
-
-	player.Set("Character", datamodel.ValueReference{
-		Reference: hum.Ref,
-		Instance:  hum,
-	})
-
-	playerGui, _ := datamodel.NewInstance("PlayerGui", nil)
-	playerGui.Ref = client.Server.InstanceDictionary.NewReference()
-	err = player.AddChild(playerGui)
-	if err != nil {
-		return err
-	}
-
-	return client.DataModel.FindService("Players").AddChild(player)
-}
-
 func (client *ServerClient) authHandler(e *emitter.Event) {
+	ticket := e.Args[0].(*Packet8ALayer)
+	if !client.checkPlayerBan(ticket) || !client.verifyTicket(ticket) {
+		return
+	}
 	err := client.WritePacket(&Packet97Layer{
 		Schema: client.Context.NetworkSchema,
 	})
@@ -200,6 +136,12 @@ func (client *ServerClient) authHandler(e *emitter.Event) {
 		return
 	}
 
+	err = client.sendIDChallenge()
+	if err != nil {
+		println("id challenge error: ", err.Error())
+		return
+	}
+
 	err = client.topReplicate()
 	if err != nil {
 		println("topreplic error: ", err.Error())
@@ -230,6 +172,9 @@ func (client *ServerClient) authHandler(e *emitter.Event) {
 		println("joindata error: ", err.Error())
 		return
 	}
+	if client.StreamingEnabled() {
+		client.startStreaming()
+	}
 
 	err = client.createCameraScript(client.Player.FindFirstChild("PlayerGui"))
 	if err != nil {
@@ -247,6 +192,13 @@ func (client *ServerClient) bindDefaultHandlers() {
 	pEmitter.On("ID_CONNECTION_REQUEST", client.connectionRequestHandler, emitter.Void)
 	pEmitter.On("ID_PROTOCOL_SYNC", client.requestParamsHandler, emitter.Void)
 	pEmitter.On("ID_SUBMIT_TICKET", client.authHandler, emitter.Void)
+	pEmitter.On("ID_PHYSICS", client.physicsHandler, emitter.Void)
+	client.DataEmitter.On("ID_REPLIC_ROCKY", client.idResponseHandler, emitter.Void)
+	pEmitter.On("ID_CHAT_ALL", client.chatHandler, emitter.Void)
+	pEmitter.On("ID_CHAT_TEAM", client.chatHandler, emitter.Void)
+	pEmitter.On("ID_CHAT_PLAYER", client.chatHandler, emitter.Void)
+	client.DataEmitter.On("ID_REPLIC_REQUEST_CHAR", client.requestCharHandler, emitter.Void)
+	client.BindDefaultDataModelHandlers()
 
 	client.PacketLogicHandler.bindDefaultHandlers()
 }
@@ -0,0 +1,102 @@
+package peer
+
+import (
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+)
+
+// ClientChangeType is the type of a change requested by a client
+type ClientChangeType uint8
+
+const (
+	// ClientChangeNewInstance is requested using ID_REPLIC_NEW_INSTANCE or ID_REPLIC_JOIN_DATA
+	ClientChangeNewInstance ClientChangeType = iota
+	// ClientChangeProperty is requested using ID_REPLIC_PROP
+	ClientChangeProperty
+	// ClientChangeParent is requested using ID_REPLIC_PROP or ID_REPLIC_ATOMIC
+	ClientChangeParent
+	// ClientChangeEvent is requested using ID_REPLIC_EVENT
+	ClientChangeEvent
+	// ClientChangeDelete is requested using ID_REPLIC_DELETE_INSTANCE
+	ClientChangeDelete
+)
+
+// ClientChange describes a change to the DataModel requested by a client
+type ClientChange struct {
+	Type     ClientChangeType
+	Instance *datamodel.Instance
+	// Name is the name of the property or the event.
+	// It is empty for other types of changes.
+	Name string
+	// Parent is the new parent for new instances and parent changes
+	Parent *datamodel.Instance
+	// Packet is the subpacket that requested the change
+	Packet Packet83Subpacket
+}
+
+// ReplicationPermission decides whether a change requested by a client
+// should be applied to the server's DataModel. Changes that are applied
+// will be replicated to the other clients. Rejected changes are emitted
+// as "rejected" on the client's GenericEvents. Under FilteringEnabled,
+// the server's value of a rejected property is sent back to the client.
+type ReplicationPermission func(client *ServerClient, change *ClientChange) bool
+
+// DefaultReplicationPermission implements filtering-enabled semantics
+// if FilteringEnabled is set in the server config: events are always
+// allowed, properties may only be changed on the client's Player
+// and on assemblies the client is the network owner of, and all other
+// changes are rejected. If FilteringEnabled isn't set, all changes
+// are allowed.
+func DefaultReplicationPermission(client *ServerClient, change *ClientChange) bool {
+	if !client.Server.Config.FilteringEnabled {
+		return true
+	}
+	switch change.Type {
+	case ClientChangeEvent:
+		return true
+	case ClientChangeProperty:
+		if client.Player != nil && change.Instance.HasAncestor(client.Player) {
+			return true
+		}
+		return client.Server.NetworkOwner(change.Instance) == client
+	default:
+		return false
+	}
+}
+
+// isChangeAllowed checks the change against the server's ReplicationPermission.
+// Rejected changes are emitted as "rejected" on the client's GenericEvents.
+func (client *ServerClient) isChangeAllowed(change *ClientChange) bool {
+	if change.Instance == nil {
+		// Unknown instance
+		<-client.GenericEvents.Emit("rejected", change)
+		return false
+	}
+	permission := client.Server.Config.Permission
+	if permission == nil {
+		permission = DefaultReplicationPermission
+	}
+	if permission(client, change) {
+		return true
+	}
+	<-client.GenericEvents.Emit("rejected", change)
+	return false
+}
+
+// revertProperty sends the server's value of a property back to the client
+// whose change to it was rejected under FilteringEnabled. Otherwise the client
+// would keep its own value and diverge from the server.
+func (client *ServerClient) revertProperty(packet *Packet83_03) {
+	if !client.Server.Config.FilteringEnabled || packet.Instance == nil || client.isStreamedOut(packet.Instance) {
+		return
+	}
+	value := packet.Instance.Get(packet.Schema.Name)
+	if value == nil {
+		// The server doesn't know the value either
+		return
+	}
+	client.WriteDataPackets(&Packet83_03{
+		Instance: packet.Instance,
+		Schema:   packet.Schema,
+		Value:    value,
+	})
+}
@@ -0,0 +1,74 @@
+package peer
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/olebedev/emitter"
+	"github.com/robloxapi/rbxfile"
+)
+
+func TestRejectedPropertyChange(t *testing.T) {
+	server := &CustomServer{
+		Config:         DefaultServerConfig(),
+		Context:        NewCommunicationContext(),
+		RunningContext: context.Background(),
+		networkOwners:  make(map[*datamodel.Instance]*ServerClient),
+		pendingPhysics: make(map[*datamodel.Instance]*pendingPhysics),
+	}
+	server.Config.FilteringEnabled = true
+	client := newServerClient(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}, server, server.Context)
+	client.BindDefaultDataModelHandlers()
+
+	workspace, _ := datamodel.NewInstance("Workspace", nil)
+	part, _ := datamodel.NewInstance("Part", workspace)
+	part.Ref = datamodel.Reference{Scope: ServerScope, Id: 1, PeerId: 1}
+	part.Set("Transparency", rbxfile.ValueFloat(0))
+	schema := &NetworkPropertySchema{Name: "Transparency", Type: PropertyTypeFloat}
+	server.Context.NetworkSchema = &NetworkSchema{Properties: []*NetworkPropertySchema{schema}}
+	var sent []*Packet83_03
+	client.DefaultPacketWriter.LayerEmitter.On("full-reliable", func(e *emitter.Event) {
+		for _, subpacket := range e.Args[0].(*PacketLayers).Main.(*Packet83Layer).SubPackets {
+			if prop, ok := subpacket.(*Packet83_03); ok {
+				sent = append(sent, prop)
+			}
+		}
+	}, emitter.Void)
+	var rejected []*ClientChange
+	client.GenericEvents.On("rejected", func(e *emitter.Event) {
+		rejected = append(rejected, e.Args[0].(*ClientChange))
+	}, emitter.Void)
+
+	changeTransparency := func(value float32) {
+		<-client.DataEmitter.Emit("ID_REPLIC_PROP", &Packet83_03{
+			Instance: part,
+			Schema:   schema,
+			Value:    rbxfile.ValueFloat(value),
+		}, &PacketLayers{})
+	}
+
+	changeTransparency(1)
+	if value := part.Get("Transparency"); value != rbxfile.ValueFloat(0) {
+		t.Errorf("disallowed change was applied: %v", value)
+	}
+	if len(rejected) != 1 || rejected[0].Instance != part || rejected[0].Name != "Transparency" {
+		t.Errorf("rejection wasn't emitted: %v", rejected)
+	}
+	if len(sent) != 1 || sent[0].Instance != part || sent[0].Value != rbxfile.ValueFloat(0) {
+		t.Errorf("server value wasn't sent back to the client: %v", sent)
+	}
+
+	server.SetNetworkOwner(part, client)
+	changeTransparency(0.5)
+	if value := part.Get("Transparency"); value != rbxfile.ValueFloat(0.5) {
+		t.Errorf("change by the network owner wasn't applied: %v", value)
+	}
+	if len(rejected) != 1 {
+		t.Errorf("allowed change was rejected: %v", rejected[1:])
+	}
+	if len(sent) != 1 {
+		t.Errorf("allowed change was sent back to the client: %v", sent[1:])
+	}
+}
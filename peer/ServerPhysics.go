@@ -0,0 +1,193 @@
+package peer
+
+import (
+	"time"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/olebedev/emitter"
+)
+
+type pendingPhysics struct {
+	owner     *ServerClient
+	subpacket *Packet85LayerSubpacket
+	// root is the assembly that the subpacket belongs to
+	root *datamodel.Instance
+}
+
+// assemblyRoot returns the instance that network ownership is tracked for.
+// Joints aren't simulated, so each Workspace child is considered
+// to be one assembly.
+func assemblyRoot(inst *datamodel.Instance) *datamodel.Instance {
+	if unit := streamingUnit(inst); unit != nil {
+		return unit
+	}
+	return inst
+}
+
+// SetNetworkOwner makes the client the network owner of the assembly
+// that contains inst. If client is nil, the server becomes the network owner.
+// Ownership is released when the assembly is removed from the DataModel,
+// for example when a character respawns.
+func (myServer *CustomServer) SetNetworkOwner(inst *datamodel.Instance, client *ServerClient) {
+	root := assemblyRoot(inst)
+	myServer.physicsMutex.Lock()
+	// Server-owned assemblies are kept in the map so that
+	// they won't be watched twice
+	_, watched := myServer.networkOwners[root]
+	myServer.networkOwners[root] = client
+	myServer.physicsMutex.Unlock()
+	if watched {
+		return
+	}
+	root.ParentEmitter.On("*", func(e *emitter.Event) {
+		if e.Args[0].(*datamodel.Instance) == nil {
+			myServer.releaseAssembly(root)
+		}
+	}, emitter.Void)
+}
+
+// NetworkOwner returns the client that owns the assembly that contains inst.
+// If the server owns the assembly, it returns nil.
+func (myServer *CustomServer) NetworkOwner(inst *datamodel.Instance) *ServerClient {
+	myServer.physicsMutex.Lock()
+	defer myServer.physicsMutex.Unlock()
+	return myServer.networkOwners[assemblyRoot(inst)]
+}
+
+// releaseAssembly forgets the owner and the pending physics of a removed assembly
+func (myServer *CustomServer) releaseAssembly(root *datamodel.Instance) {
+	myServer.physicsMutex.Lock()
+	defer myServer.physicsMutex.Unlock()
+	delete(myServer.networkOwners, root)
+	for inst, update := range myServer.pendingPhysics {
+		if update.root == root {
+			delete(myServer.pendingPhysics, inst)
+		}
+	}
+}
+
+func (myServer *CustomServer) releaseNetworkOwnership(client *ServerClient) {
+	myServer.physicsMutex.Lock()
+	defer myServer.physicsMutex.Unlock()
+	for root, owner := range myServer.networkOwners {
+		if owner == client {
+			myServer.networkOwners[root] = nil
+		}
+	}
+	for inst, update := range myServer.pendingPhysics {
+		if update.owner == client {
+			delete(myServer.pendingPhysics, inst)
+		}
+	}
+}
+
+// applyPhysicsData updates the physics properties of the instance. The properties
+// are set directly so that they won't be replicated using ID_REPLIC_PROP.
+func applyPhysicsData(data *PhysicsData) {
+	if data.Instance == nil {
+		return
+	}
+	data.Instance.PropertiesMutex.Lock()
+	data.Instance.Properties["CFrame"] = data.CFrame
+	data.Instance.Properties["Velocity"] = data.LinearVelocity
+	data.Instance.Properties["RotVelocity"] = data.RotationalVelocity
+	data.Instance.PropertiesMutex.Unlock()
+}
+
+func (client *ServerClient) physicsHandler(e *emitter.Event) {
+	server := client.Server
+	for _, subpacket := range e.Args[0].(*Packet85Layer).SubPackets {
+		inst := subpacket.Data.Instance
+		if inst == nil {
+			continue
+		}
+		if server.NetworkOwner(inst) != client {
+			println("ignoring physics from non-owner", client.Address.String(), "for", inst.GetFullName())
+			continue
+		}
+
+		applyPhysicsData(&subpacket.Data)
+		for _, child := range subpacket.Children {
+			applyPhysicsData(child)
+		}
+
+		server.physicsMutex.Lock()
+		server.pendingPhysics[inst] = &pendingPhysics{
+			owner:     client,
+			subpacket: subpacket,
+			root:      assemblyRoot(inst),
+		}
+		server.physicsMutex.Unlock()
+	}
+}
+
+// broadcastSubpacket converts a physics subpacket received from a client
+// to the format that is sent by the server
+func broadcastSubpacket(subpacket *Packet85LayerSubpacket, interval float32) *Packet85LayerSubpacket {
+	data := subpacket.Data
+	return &Packet85LayerSubpacket{
+		Data: PhysicsData{
+			Instance: data.Instance,
+			Motors:   data.Motors,
+		},
+		NetworkHumanoidState: subpacket.NetworkHumanoidState,
+		Children:             subpacket.Children,
+		History: []*PhysicsData{{
+			CFrame:             data.CFrame,
+			LinearVelocity:     data.LinearVelocity,
+			RotationalVelocity: data.RotationalVelocity,
+			PlatformChild:      data.PlatformChild,
+			Interval:           interval,
+		}},
+	}
+}
+
+func (myServer *CustomServer) broadcastPhysics() {
+	myServer.physicsMutex.Lock()
+	pending := myServer.pendingPhysics
+	myServer.pendingPhysics = make(map[*datamodel.Instance]*pendingPhysics)
+	myServer.physicsMutex.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	interval := float32(myServer.Config.PhysicsBroadcastInterval.Seconds())
+	for _, client := range myServer.clientList() {
+		if client.Player == nil {
+			// Client hasn't finished joining
+			continue
+		}
+		subpackets := make([]*Packet85LayerSubpacket, 0, len(pending))
+		for inst, update := range pending {
+			if update.owner == client || client.isStreamedOut(inst) {
+				continue
+			}
+			subpackets = append(subpackets, broadcastSubpacket(update.subpacket, interval))
+		}
+		if len(subpackets) == 0 {
+			continue
+		}
+		err := client.WritePacket(&Packet85Layer{SubPackets: subpackets})
+		if err != nil {
+			println("physics error: ", err.Error())
+		}
+	}
+}
+
+func (myServer *CustomServer) startPhysicsBroadcast() {
+	if myServer.Config.PhysicsBroadcastInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(myServer.Config.PhysicsBroadcastInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				myServer.broadcastPhysics()
+			case <-myServer.RunningContext.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package peer
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/olebedev/emitter"
+)
+
+func TestNetworkOwnerReleasedOnRemoval(t *testing.T) {
+	server := &CustomServer{
+		networkOwners:  make(map[*datamodel.Instance]*ServerClient),
+		pendingPhysics: make(map[*datamodel.Instance]*pendingPhysics),
+	}
+	client := &ServerClient{
+		Server:  server,
+		Address: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1},
+	}
+	workspace, _ := datamodel.NewInstance("Workspace", nil)
+	character, _ := datamodel.NewInstance("Model", workspace)
+	rootPart, _ := datamodel.NewInstance("Part", character)
+
+	server.SetNetworkOwner(rootPart, client)
+	if server.NetworkOwner(character) != client {
+		t.Fatal("client doesn't own the character")
+	}
+
+	// The server owns the assembly for a while, which must not
+	// affect releasing it
+	server.SetNetworkOwner(character, nil)
+	server.SetNetworkOwner(character, client)
+
+	client.physicsHandler(&emitter.Event{Args: []interface{}{&Packet85Layer{
+		SubPackets: []*Packet85LayerSubpacket{{Data: PhysicsData{Instance: rootPart}}},
+	}}})
+	if len(server.pendingPhysics) != 1 {
+		t.Fatalf("physics from the owner wasn't queued: %d pending", len(server.pendingPhysics))
+	}
+
+	character.Destroy()
+	if len(server.networkOwners) != 0 || len(server.pendingPhysics) != 0 {
+		t.Errorf("removed character is still tracked: %d owners, %d pending", len(server.networkOwners), len(server.pendingPhysics))
+	}
+}
@@ -0,0 +1,209 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/robloxapi/rbxfile"
+)
+
+// RemoteEventHandler is called when a client fires a RemoteEvent.
+// It is called from the server's read loop, so it shouldn't block.
+type RemoteEventHandler func(client *ServerClient, args datamodel.ValueTuple)
+
+// RemoteFunctionHandler is called in a new goroutine when a client invokes
+// a RemoteFunction. The results are sent back to the client, or if err
+// is not nil, the invocation fails with err as the error message.
+type RemoteFunctionHandler func(client *ServerClient, args datamodel.ValueTuple) (datamodel.ValueTuple, error)
+
+type remoteResult struct {
+	results datamodel.ValueTuple
+	err     error
+}
+
+func (myServer *CustomServer) findRemote(path string, className string) (*datamodel.Instance, error) {
+	remote := myServer.Context.DataModel.FindInstance(path)
+	if remote == nil {
+		return nil, fmt.Errorf("%s not found", path)
+	}
+	if remote.ClassName != className {
+		return nil, fmt.Errorf("%s is a %s, not a %s", path, remote.ClassName, className)
+	}
+	return remote, nil
+}
+
+// OnRemoteEvent registers a handler for the RemoteEvent at the given path, for example
+// "ReplicatedStorage.Remotes.Event". The path must begin with the class name of a service.
+func (myServer *CustomServer) OnRemoteEvent(path string, handler RemoteEventHandler) error {
+	remote, err := myServer.findRemote(path, "RemoteEvent")
+	if err != nil {
+		return err
+	}
+	myServer.remoteMutex.Lock()
+	myServer.remoteEvents[remote] = append(myServer.remoteEvents[remote], handler)
+	myServer.remoteMutex.Unlock()
+	return nil
+}
+
+// HandleRemoteFunction sets the handler for the RemoteFunction at the given path,
+// replacing any existing handler. The path is resolved like in OnRemoteEvent().
+func (myServer *CustomServer) HandleRemoteFunction(path string, handler RemoteFunctionHandler) error {
+	remote, err := myServer.findRemote(path, "RemoteFunction")
+	if err != nil {
+		return err
+	}
+	myServer.remoteMutex.Lock()
+	myServer.remoteFunctions[remote] = handler
+	myServer.remoteMutex.Unlock()
+	return nil
+}
+
+// FireAllClients fires the RemoteEvent for every client
+func (myServer *CustomServer) FireAllClients(remote *datamodel.Instance, args ...rbxfile.Value) {
+	// The event will be replicated to every client by the event handlers
+	remote.FireEvent("OnClientEvent", datamodel.ValueTuple(args))
+}
+
+func (client *ServerClient) writeRemoteEvent(remote *datamodel.Instance, name string, args ...rbxfile.Value) error {
+	return client.WriteDataPackets(&Packet83_07{
+		Instance: remote,
+		Schema:   client.Context.NetworkSchema.SchemaForClass(remote.ClassName).SchemaForEvent(name),
+		Event:    &ReplicationEvent{args},
+	})
+}
+
+// FireClient fires the RemoteEvent for this client only
+func (client *ServerClient) FireClient(remote *datamodel.Instance, args ...rbxfile.Value) error {
+	return client.writeRemoteEvent(remote, "OnClientEvent", datamodel.ValueTuple(args))
+}
+
+// InvokeClient invokes the RemoteFunction on the client and waits for the results
+func (client *ServerClient) InvokeClient(ctx context.Context, remote *datamodel.Instance, args ...rbxfile.Value) (datamodel.ValueTuple, error) {
+	if client.Player == nil {
+		return nil, errors.New("player hasn't been created")
+	}
+	result := make(chan remoteResult, 1)
+	client.remoteMutex.Lock()
+	client.invocationIndex++
+	callID := client.invocationIndex
+	client.pendingInvocations[callID] = result
+	client.remoteMutex.Unlock()
+	defer func() {
+		client.remoteMutex.Lock()
+		delete(client.pendingInvocations, callID)
+		client.remoteMutex.Unlock()
+	}()
+
+	err := client.writeRemoteEvent(remote, "RemoteOnInvokeClient",
+		rbxfile.ValueInt(callID),
+		datamodel.ValueReference{Instance: client.Player, Reference: client.Player.Ref},
+		datamodel.ValueTuple(args),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-result:
+		return res.results, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-client.RunningContext.Done():
+		return nil, errors.New("client disconnected")
+	}
+}
+
+func tupleArgument(args []rbxfile.Value, index int) datamodel.ValueTuple {
+	if index >= len(args) {
+		return nil
+	}
+	tuple, _ := args[index].(datamodel.ValueTuple)
+	return tuple
+}
+
+func intArgument(args []rbxfile.Value, index int) (int32, bool) {
+	if index >= len(args) {
+		return 0, false
+	}
+	value, ok := args[index].(rbxfile.ValueInt)
+	return int32(value), ok
+}
+
+// replyToInvocation sends the results of a RemoteFunction invoked by the client,
+// or the error message if err is not nil
+func (client *ServerClient) replyToInvocation(remote *datamodel.Instance, callID int32, results datamodel.ValueTuple, err error) {
+	if err != nil {
+		err = client.writeRemoteEvent(remote, "RemoteOnInvokeError", rbxfile.ValueInt(callID), rbxfile.ValueString(err.Error()))
+	} else {
+		err = client.writeRemoteEvent(remote, "RemoteOnInvokeSuccess", rbxfile.ValueInt(callID), results)
+	}
+	if err != nil {
+		println("remote function error: ", err.Error())
+	}
+}
+
+func (client *ServerClient) invokeRemoteFunction(remote *datamodel.Instance, handler RemoteFunctionHandler, callID int32, args datamodel.ValueTuple) {
+	results, err := handler(client, args)
+	client.replyToInvocation(remote, callID, results, err)
+}
+
+func (client *ServerClient) resolveInvocation(callID int32, result remoteResult) {
+	client.remoteMutex.Lock()
+	pending, ok := client.pendingInvocations[callID]
+	client.remoteMutex.Unlock()
+	if !ok {
+		return
+	}
+	// Duplicate results are dropped
+	select {
+	case pending <- result:
+	default:
+	}
+}
+
+// dispatchRemote calls the handlers registered for remote events
+// and functions fired by the client
+func (client *ServerClient) dispatchRemote(packet *Packet83_07) {
+	server := client.Server
+	remote := packet.Instance
+	args := packet.Event.Arguments
+	switch packet.Schema.Name {
+	case "OnServerEvent":
+		server.remoteMutex.RLock()
+		handlers := server.remoteEvents[remote]
+		server.remoteMutex.RUnlock()
+		// Arguments: Player, Tuple
+		for _, handler := range handlers {
+			handler(client, tupleArgument(args, 1))
+		}
+	case "RemoteOnInvokeServer":
+		// Arguments: call ID, Player, Tuple
+		callID, ok := intArgument(args, 0)
+		if !ok {
+			return
+		}
+		server.remoteMutex.RLock()
+		handler, ok := server.remoteFunctions[remote]
+		server.remoteMutex.RUnlock()
+		if !ok {
+			// Otherwise InvokeServer would never return on the client
+			client.replyToInvocation(remote, callID, nil, fmt.Errorf("no handler for %s", remote.GetFullName()))
+			return
+		}
+		go client.invokeRemoteFunction(remote, handler, callID, tupleArgument(args, 2))
+	case "RemoteOnInvokeSuccess":
+		// Arguments: call ID, Tuple
+		callID, ok := intArgument(args, 0)
+		if ok {
+			client.resolveInvocation(callID, remoteResult{results: tupleArgument(args, 1)})
+		}
+	case "RemoteOnInvokeError":
+		// Arguments: call ID, error message
+		callID, ok := intArgument(args, 0)
+		if ok && len(args) > 1 {
+			client.resolveInvocation(callID, remoteResult{err: errors.New(args[1].String())})
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/olebedev/emitter"
+	"github.com/robloxapi/rbxfile"
+)
+
+// remoteTestSchema returns a schema containing the events of remotes
+func remoteTestSchema() *NetworkSchema {
+	arguments := func(types ...uint8) []*NetworkArgumentSchema {
+		schemas := make([]*NetworkArgumentSchema, len(types))
+		for i, typ := range types {
+			schemas[i] = &NetworkArgumentSchema{Type: typ}
+		}
+		return schemas
+	}
+	schema := &NetworkSchema{}
+	addClass := func(name string, events map[string][]*NetworkArgumentSchema) {
+		class := &NetworkInstanceSchema{Name: name, NetworkID: uint16(len(schema.Instances))}
+		for eventName, eventArguments := range events {
+			event := &NetworkEventSchema{
+				Name:           eventName,
+				Arguments:      eventArguments,
+				InstanceSchema: class,
+				NetworkID:      uint16(len(schema.Events)),
+			}
+			class.Events = append(class.Events, event)
+			schema.Events = append(schema.Events, event)
+		}
+		schema.Instances = append(schema.Instances, class)
+	}
+	addClass("RemoteEvent", map[string][]*NetworkArgumentSchema{
+		"OnClientEvent": arguments(PropertyTypeTuple),
+		"OnServerEvent": arguments(PropertyTypeInstance, PropertyTypeTuple),
+	})
+	addClass("RemoteFunction", map[string][]*NetworkArgumentSchema{
+		"RemoteOnInvokeClient":  arguments(PropertyTypeInt, PropertyTypeInstance, PropertyTypeTuple),
+		"RemoteOnInvokeServer":  arguments(PropertyTypeInt, PropertyTypeInstance, PropertyTypeTuple),
+		"RemoteOnInvokeSuccess": arguments(PropertyTypeInt, PropertyTypeTuple),
+		"RemoteOnInvokeError":   arguments(PropertyTypeInt, PropertyTypeString),
+	})
+	return schema
+}
+
+// remoteTest contains a server with one client and the remotes
+// in ReplicatedStorage
+type remoteTest struct {
+	server   *CustomServer
+	client   *ServerClient
+	event    *datamodel.Instance
+	function *datamodel.Instance
+	// sent receives the events written to the client
+	sent chan *Packet83_07
+}
+
+func newRemoteTest() *remoteTest {
+	test := &remoteTest{sent: make(chan *Packet83_07, 8)}
+	test.server = &CustomServer{
+		Config:          DefaultServerConfig(),
+		Context:         NewCommunicationContext(),
+		RunningContext:  context.Background(),
+		remoteEvents:    make(map[*datamodel.Instance][]RemoteEventHandler),
+		remoteFunctions: make(map[*datamodel.Instance]RemoteFunctionHandler),
+	}
+	test.server.Context.NetworkSchema = remoteTestSchema()
+	storage, _ := datamodel.NewInstance("ReplicatedStorage", nil)
+	storage.IsService = true
+	test.server.Context.DataModel.AddService(storage)
+	newRemote := func(className string, id uint32) *datamodel.Instance {
+		remote, _ := datamodel.NewInstance(className, storage)
+		remote.Set("Name", rbxfile.ValueString(className))
+		remote.Ref = datamodel.Reference{Scope: ServerScope, Id: id, PeerId: 1}
+		return remote
+	}
+	test.event = newRemote("RemoteEvent", 1)
+	test.function = newRemote("RemoteFunction", 2)
+
+	test.client = newServerClient(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}, test.server, test.server.Context)
+	test.client.Player, _ = datamodel.NewInstance("Player", nil)
+	test.client.Player.Ref = datamodel.Reference{Scope: ServerScope, Id: 3, PeerId: 1}
+	test.client.DefaultPacketWriter.LayerEmitter.On("full-reliable", func(e *emitter.Event) {
+		for _, subpacket := range e.Args[0].(*PacketLayers).Main.(*Packet83Layer).SubPackets {
+			if event, ok := subpacket.(*Packet83_07); ok {
+				test.sent <- event
+			}
+		}
+	}, emitter.Void)
+	return test
+}
+
+// expectSent returns the next event written to the client
+func (test *remoteTest) expectSent(t *testing.T, name string) []rbxfile.Value {
+	select {
+	case event := <-test.sent:
+		if event.Schema.Name != name {
+			t.Fatalf("expected %s, got %s", name, event.Schema.Name)
+		}
+		return event.Event.Arguments
+	case <-time.After(time.Second):
+		t.Fatalf("%s wasn't sent", name)
+		return nil
+	}
+}
+
+// receive dispatches an event fired by the client
+func (test *remoteTest) receive(remote *datamodel.Instance, name string, args ...rbxfile.Value) {
+	test.client.dispatchRemote(&Packet83_07{
+		Instance: remote,
+		Schema:   test.server.Context.NetworkSchema.SchemaForClass(remote.ClassName).SchemaForEvent(name),
+		Event:    &ReplicationEvent{Arguments: args},
+	})
+}
+
+func TestRemoteEvent(t *testing.T) {
+	test := newRemoteTest()
+	var received []datamodel.ValueTuple
+	err := test.server.OnRemoteEvent("ReplicatedStorage.RemoteEvent", func(client *ServerClient, args datamodel.ValueTuple) {
+		if client != test.client {
+			t.Error("handler was called with the wrong client")
+		}
+		received = append(received, args)
+	})
+	if err != nil {
+		t.Fatalf("failed to register handler: %s", err.Error())
+	}
+	if test.server.OnRemoteEvent("ReplicatedStorage.RemoteFunction", nil) == nil {
+		t.Error("handler was registered for a RemoteFunction")
+	}
+
+	test.receive(test.event, "OnServerEvent",
+		datamodel.ValueReference{Instance: test.client.Player, Reference: test.client.Player.Ref},
+		datamodel.ValueTuple{rbxfile.ValueString("hello")},
+	)
+	if len(received) != 1 || len(received[0]) != 1 || received[0][0].String() != "hello" {
+		t.Errorf("handler wasn't called with the arguments: %v", received)
+	}
+
+	err = test.client.FireClient(test.event, rbxfile.ValueString("hi"))
+	if err != nil {
+		t.Fatalf("failed to fire event: %s", err.Error())
+	}
+	args := test.expectSent(t, "OnClientEvent")
+	if tuple, ok := args[0].(datamodel.ValueTuple); !ok || len(tuple) != 1 || tuple[0].String() != "hi" {
+		t.Errorf("unexpected arguments %v", args)
+	}
+}
+
+func TestInvokeServer(t *testing.T) {
+	test := newRemoteTest()
+	player := datamodel.ValueReference{Instance: test.client.Player, Reference: test.client.Player.Ref}
+
+	// Without a handler, the client must still get a reply
+	test.receive(test.function, "RemoteOnInvokeServer", rbxfile.ValueInt(1), player, datamodel.ValueTuple{})
+	args := test.expectSent(t, "RemoteOnInvokeError")
+	if args[0] != rbxfile.ValueInt(1) || !strings.Contains(args[1].String(), "no handler") {
+		t.Errorf("unexpected error reply %v", args)
+	}
+
+	err := test.server.HandleRemoteFunction("ReplicatedStorage.RemoteFunction", func(client *ServerClient, args datamodel.ValueTuple) (datamodel.ValueTuple, error) {
+		if len(args) == 0 {
+			return nil, errors.New("no arguments")
+		}
+		return datamodel.ValueTuple{args[0]}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to set handler: %s", err.Error())
+	}
+
+	test.receive(test.function, "RemoteOnInvokeServer", rbxfile.ValueInt(2), player, datamodel.ValueTuple{rbxfile.ValueInt(5)})
+	args = test.expectSent(t, "RemoteOnInvokeSuccess")
+	if tuple, ok := args[1].(datamodel.ValueTuple); args[0] != rbxfile.ValueInt(2) || !ok || len(tuple) != 1 || tuple[0] != rbxfile.ValueInt(5) {
+		t.Errorf("unexpected success reply %v", args)
+	}
+
+	test.receive(test.function, "RemoteOnInvokeServer", rbxfile.ValueInt(3), player, datamodel.ValueTuple{})
+	args = test.expectSent(t, "RemoteOnInvokeError")
+	if args[0] != rbxfile.ValueInt(3) || args[1].String() != "no arguments" {
+		t.Errorf("unexpected error reply %v", args)
+	}
+}
+
+func TestInvokeClient(t *testing.T) {
+	test := newRemoteTest()
+	// reply answers the next invocation with the event
+	reply := func(name string, result rbxfile.Value) {
+		go func() {
+			invocation := <-test.sent
+			test.receive(test.function, name, invocation.Event.Arguments[0], result)
+		}()
+	}
+
+	reply("RemoteOnInvokeSuccess", datamodel.ValueTuple{rbxfile.ValueString("result")})
+	results, err := test.client.InvokeClient(context.Background(), test.function, rbxfile.ValueInt(1))
+	if err != nil || len(results) != 1 || results[0].String() != "result" {
+		t.Errorf("unexpected results %v, %v", results, err)
+	}
+
+	reply("RemoteOnInvokeError", rbxfile.ValueString("failed"))
+	_, err = test.client.InvokeClient(context.Background(), test.function)
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("expected the error from the client, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = test.client.InvokeClient(ctx, test.function)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	test.expectSent(t, "RemoteOnInvokeClient")
+	if len(test.client.pendingInvocations) != 0 {
+		t.Errorf("%d invocations weren't forgotten", len(test.client.pendingInvocations))
+	}
+}
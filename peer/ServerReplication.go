@@ -66,12 +66,12 @@ func (cont *ReplicationContainer) updateBinding(client *ServerClient, isNew bool
 	}
 }
 
-func joinDataConfigForInstance(inst *datamodel.Instance) *joinDataConfig {
+func (myServer *CustomServer) joinDataConfigForInstance(inst *datamodel.Instance) *JoinDataConfig {
 	for inst.Parent() != nil && inst.Parent().ClassName != "DataModel" {
 		inst = inst.Parent()
 	}
 
-	for _, config := range joinDataConfiguration {
+	for _, config := range myServer.Config.JoinData {
 		if config.ClassName == inst.ClassName {
 			return &config
 		}
@@ -82,18 +82,37 @@ func joinDataConfigForInstance(inst *datamodel.Instance) *joinDataConfig {
 
 // BindDefaultDataModelHandlers binds the client's DataModel
 // handlers so that the client's changes will be reflected in
-// the DataModel
+// the DataModel. Changes are checked using the server's ReplicationPermission
+// and replicated to the other clients once they have been applied.
 func (client *ServerClient) BindDefaultDataModelHandlers() {
 	dataEmitter := client.DataEmitter
 	dataEmitter.On("ID_REPLIC_DELETE_INSTANCE", func(e *emitter.Event) {
-		inst := e.Args[0].(*Packet83_01).Instance
+		packet := e.Args[0].(*Packet83_01)
+		inst := packet.Instance
+		if !client.isChangeAllowed(&ClientChange{
+			Type:     ClientChangeDelete,
+			Instance: inst,
+			Packet:   packet,
+		}) {
+			return
+		}
 		client.handlingRemoval = inst
-		client.DefaultPacketReader.HandlePacket01(e)
+		// The default handler ignores deletion requests from clients
+		client.Context.removeInstance(inst)
 		client.handlingRemoval = nil
 	}, emitter.Void)
 
 	dataEmitter.On("ID_REPLIC_NEW_INSTANCE", func(e *emitter.Event) {
-		inst := e.Args[0].(*Packet83_02).ReplicationInstance.Instance
+		packet := e.Args[0].(*Packet83_02)
+		inst := packet.ReplicationInstance.Instance
+		if !client.isChangeAllowed(&ClientChange{
+			Type:     ClientChangeNewInstance,
+			Instance: inst,
+			Parent:   packet.ReplicationInstance.Parent,
+			Packet:   packet,
+		}) {
+			return
+		}
 		client.handlingChild = inst
 		client.handlingProp = handledChange{
 			Instance: inst,
@@ -108,13 +127,27 @@ func (client *ServerClient) BindDefaultDataModelHandlers() {
 
 	dataEmitter.On("ID_REPLIC_PROP", func(e *emitter.Event) {
 		packet := e.Args[0].(*Packet83_03)
-		propName := "Parent"
+		change := &ClientChange{
+			Type:     ClientChangeParent,
+			Instance: packet.Instance,
+			Name:     "Parent",
+			Packet:   packet,
+		}
 		if packet.Schema != nil {
-			propName = packet.Schema.Name
+			change.Type = ClientChangeProperty
+			change.Name = packet.Schema.Name
+		} else if parent, ok := packet.Value.(datamodel.ValueReference); ok {
+			change.Parent = parent.Instance
+		}
+		if !client.isChangeAllowed(change) {
+			if change.Type == ClientChangeProperty {
+				client.revertProperty(packet)
+			}
+			return
 		}
 		client.handlingProp = handledChange{
 			Instance: packet.Instance,
-			Name:     propName,
+			Name:     change.Name,
 		}
 		client.DefaultPacketReader.HandlePacket03(e)
 		client.handlingProp = handledChange{}
@@ -122,24 +155,41 @@ func (client *ServerClient) BindDefaultDataModelHandlers() {
 
 	dataEmitter.On("ID_REPLIC_EVENT", func(e *emitter.Event) {
 		packet := e.Args[0].(*Packet83_07)
+		if packet.Schema == nil || !client.isChangeAllowed(&ClientChange{
+			Type:     ClientChangeEvent,
+			Instance: packet.Instance,
+			Name:     packet.Schema.Name,
+			Packet:   packet,
+		}) {
+			return
+		}
 		client.handlingEvent = handledChange{
 			Instance: packet.Instance,
 			Name:     packet.Schema.Name,
 		}
 		client.DefaultPacketReader.HandlePacket07(e)
 		client.handlingEvent = handledChange{}
+		client.dispatchRemote(packet)
 	}, emitter.Void)
 
 	dataEmitter.On("ID_REPLIC_JOIN_DATA", func(e *emitter.Event) {
-		instanceList := e.Args[0].(*Packet83_0B).Instances
-		for _, inst := range instanceList {
+		packet := e.Args[0].(*Packet83_0B)
+		for _, inst := range packet.Instances {
+			if !client.isChangeAllowed(&ClientChange{
+				Type:     ClientChangeNewInstance,
+				Instance: inst.Instance,
+				Parent:   inst.Parent,
+				Packet:   packet,
+			}) {
+				continue
+			}
 			client.handlingChild = inst.Instance
 			client.handlingProp = handledChange{
 				Instance: inst.Instance,
 				Name:     "Parent",
 			}
 
-			err := client.handleReplicationInstance(inst)
+			err := client.handleReplicationInstance(inst, e.Args[1].(*PacketLayers))
 			if err != nil {
 				e.Args[1].(*PacketLayers).Error = err
 				return
@@ -152,6 +202,15 @@ func (client *ServerClient) BindDefaultDataModelHandlers() {
 
 	dataEmitter.On("ID_REPLIC_ATOMIC", func(e *emitter.Event) {
 		packet := e.Args[0].(*Packet83_13)
+		if !client.isChangeAllowed(&ClientChange{
+			Type:     ClientChangeParent,
+			Instance: packet.Instance,
+			Name:     "Parent",
+			Parent:   packet.Parent,
+			Packet:   packet,
+		}) {
+			return
+		}
 		client.handlingChild = packet.Instance
 		client.handlingProp = handledChange{
 			Instance: packet.Instance,
@@ -190,7 +249,7 @@ func (client *ServerClient) isHandlingRemoval(inst *datamodel.Instance) bool {
 }
 
 func (client *ServerClient) parentChangedHandler(inst *datamodel.Instance, e *emitter.Event) {
-	if client.isHandlingRemoval(inst) || client.isHandlingProp(inst, "Parent") {
+	if client.isHandlingRemoval(inst) || client.isHandlingProp(inst, "Parent") || client.isStreamedOut(inst) {
 		// avoid circular replication: if this parent change
 		// comes from the client, we ignore it
 		return
@@ -248,7 +307,7 @@ func (client *ServerClient) propChangedHandler(inst *datamodel.Instance, e *emit
 	name := e.OriginalTopic
 	value := e.Args[0].(rbxfile.Value)
 
-	if !client.isHandlingProp(inst, name) {
+	if !client.isHandlingProp(inst, name) && !client.isStreamedOut(inst) {
 		client.WriteDataPackets(&Packet83_03{
 			Instance: inst,
 			Schema:   client.Context.NetworkSchema.SchemaForClass(inst.ClassName).SchemaForProp(name),
@@ -261,7 +320,7 @@ func (client *ServerClient) eventHandler(inst *datamodel.Instance, e *emitter.Ev
 	name := e.OriginalTopic
 	args := e.Args[0].([]rbxfile.Value)
 
-	if !client.isHandlingEvent(inst, name) {
+	if !client.isHandlingEvent(inst, name) && !client.isStreamedOut(inst) {
 		switch name {
 		case "RemoteOnInvokeClient", "OnClientEvent":
 			client.WriteDataPackets(&Packet83_07{
@@ -269,6 +328,8 @@ func (client *ServerClient) eventHandler(inst *datamodel.Instance, e *emitter.Ev
 				Schema:   client.Context.NetworkSchema.SchemaForClass(inst.ClassName).SchemaForEvent(name),
 				Event:    &ReplicationEvent{args},
 			})
+		case "Destroying":
+			// Fired locally by Instance.Destroy()
 		default:
 			println("Warning: not replicating non-whitelisted event", name)
 		}
@@ -302,7 +363,8 @@ func (client *ServerClient) updateBinding(inst *datamodel.Instance, canReplicate
 		}
 
 		client.replicatedInstances = append(client.replicatedInstances, newBinding)
-		if canReplicate && !client.isHandlingChild(inst) && !newBinding.hasReplicated {
+		// Streamed-out instances will be sent when their region is streamed in
+		if canReplicate && !client.isHandlingChild(inst) && !newBinding.hasReplicated && !client.isStreamedOut(inst) {
 			newBinding.hasReplicated = true
 			client.PacketLogicHandler.ReplicateInstance(inst, false)
 		} else if client.isHandlingChild(inst) {
@@ -345,7 +407,7 @@ func (client *ServerClient) sendReplicatedFirst() error {
 	})
 }
 
-func (client *ServerClient) sendContainer(streamer *JoinDataStreamer, config joinDataConfig) error {
+func (client *ServerClient) sendContainer(streamer *JoinDataStreamer, config JoinDataConfig) error {
 	service := client.DataModel.FindService(config.ClassName)
 	if service != nil {
 		repConfig := client.ReplicationConfig(service)
@@ -364,7 +426,7 @@ func (client *ServerClient) sendContainers() error {
 			println("joindata error: ", err.Error())
 		}
 	}, emitter.Void)
-	for _, dataConfig := range joinDataConfiguration {
+	for _, dataConfig := range client.Server.Config.JoinData {
 		// Previously replicated for priority, don't duplicate
 		if dataConfig.ClassName != "ReplicatedFirst" {
 			err = client.sendContainer(joinDataStreamer, dataConfig)
@@ -394,6 +456,10 @@ func (client *ServerClient) replicateJoinDataChildren(children []*datamodel.Inst
 			// Skip instances that have already been replicated
 			continue
 		}
+		if client.isStreamedOut(child) {
+			// Will be sent using ID_REPLIC_STREAM_DATA
+			continue
+		}
 		config.hasReplicated = true
 
 		err := streamer.AddInstance(client.ReplicationInstance(child, false))
@@ -0,0 +1,304 @@
+package peer
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/robloxapi/rbxfile"
+)
+
+// maxStreamChunk is the maximum number of instances sent in
+// a single ID_REPLIC_STREAM_DATA packet
+const maxStreamChunk = 0x80
+
+// streamingState keeps track of the Workspace children that have
+// been streamed to a client
+type streamingState struct {
+	mutex sync.Mutex
+	// units maps each streamed-in Workspace child to the region
+	// it was streamed in with
+	units map[*datamodel.Instance]StreamInfo
+	// focus is the last known position of the client's character
+	focus rbxfile.ValueVector3
+}
+
+// streamingUnit returns the Workspace child that contains inst,
+// or nil if inst isn't a descendant of Workspace
+func streamingUnit(inst *datamodel.Instance) *datamodel.Instance {
+	for inst != nil {
+		parent := inst.Parent()
+		if parent != nil && parent.ClassName == "Workspace" {
+			return inst
+		}
+		inst = parent
+	}
+	return nil
+}
+
+// instancePosition returns the position used for streaming the instance:
+// the position of a part, or the position of a model's PrimaryPart,
+// HumanoidRootPart or first descendant part
+func instancePosition(inst *datamodel.Instance) (rbxfile.ValueVector3, bool) {
+	if cframe, ok := inst.Get("CFrame").(rbxfile.ValueCFrame); ok {
+		return cframe.Position, true
+	}
+	if primary, ok := inst.Get("PrimaryPart").(datamodel.ValueReference); ok && primary.Instance != nil {
+		if position, ok := instancePosition(primary.Instance); ok {
+			return position, true
+		}
+	}
+	if root := inst.FindFirstChild("HumanoidRootPart"); root != nil {
+		if position, ok := instancePosition(root); ok {
+			return position, true
+		}
+	}
+	for _, child := range inst.Children {
+		if position, ok := instancePosition(child); ok {
+			return position, true
+		}
+	}
+	return rbxfile.ValueVector3{}, false
+}
+
+func regionForPosition(position rbxfile.ValueVector3, regionSize float32) StreamInfo {
+	return StreamInfo{
+		X: int32(math.Floor(float64(position.X / regionSize))),
+		Y: int32(math.Floor(float64(position.Y / regionSize))),
+		Z: int32(math.Floor(float64(position.Z / regionSize))),
+	}
+}
+
+func positionDistance(a, b rbxfile.ValueVector3) float32 {
+	dx, dy, dz := a.X-b.X, a.Y-b.Y, a.Z-b.Z
+	return float32(math.Sqrt(float64(dx*dx + dy*dy + dz*dz)))
+}
+
+// hasCapability reports whether the client requested the given capabilities
+// and the server offered them
+func (client *ServerClient) hasCapability(capability uint64) bool {
+	profile := client.Context.Profile()
+	return profile.RequestedCapabilities&capability == capability && profile.HasCapability(capability)
+}
+
+// usesStreaming reports whether Workspace should be streamed to the client
+func (client *ServerClient) usesStreaming() bool {
+	return client.Server.Config.StreamingEnabled || client.hasCapability(CapabilityDebugForceStreamingEnabled)
+}
+
+// StreamingEnabled reports whether Workspace is being streamed to the client
+func (client *ServerClient) StreamingEnabled() bool {
+	return client.streaming != nil
+}
+
+// isStreamedOut reports whether inst belongs to a part of Workspace
+// that hasn't been streamed to the client
+func (client *ServerClient) isStreamedOut(inst *datamodel.Instance) bool {
+	if client.streaming == nil {
+		return false
+	}
+	unit := streamingUnit(inst)
+	if unit == nil {
+		return false
+	}
+	client.streaming.mutex.Lock()
+	_, streamedIn := client.streaming.units[unit]
+	client.streaming.mutex.Unlock()
+	if streamedIn {
+		return false
+	}
+	// Units without a position are sent with the join data
+	_, hasPosition := instancePosition(unit)
+	return hasPosition
+}
+
+func (client *ServerClient) setHasReplicated(inst *datamodel.Instance, hasReplicated bool) {
+	config := client.ReplicationConfig(inst)
+	if config != nil {
+		config.hasReplicated = hasReplicated
+	}
+	for _, child := range inst.Children {
+		client.setHasReplicated(child, hasReplicated)
+	}
+}
+
+func (client *ServerClient) appendStreamInstances(instances []*ReplicationInstance, inst *datamodel.Instance) []*ReplicationInstance {
+	instances = append(instances, client.ReplicationInstance(inst, false))
+	for _, child := range inst.Children {
+		instances = client.appendStreamInstances(instances, child)
+	}
+	return instances
+}
+
+func (client *ServerClient) streamIn(region StreamInfo, units []*datamodel.Instance) error {
+	var instances []*ReplicationInstance
+	for _, unit := range units {
+		instances = client.appendStreamInstances(instances, unit)
+		client.setHasReplicated(unit, true)
+	}
+	for len(instances) > 0 {
+		chunk := instances
+		if len(chunk) > maxStreamChunk {
+			chunk = chunk[:maxStreamChunk]
+		}
+		instances = instances[len(chunk):]
+		err := client.WriteDataPackets(&Packet83_0D{
+			Region:    region,
+			Instances: chunk,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (client *ServerClient) streamOut(region StreamInfo, units []*datamodel.Instance) error {
+	for _, unit := range units {
+		client.setHasReplicated(unit, false)
+	}
+	return client.WriteDataPackets(&Packet83_0E{
+		Region:    region,
+		Instances: units,
+	})
+}
+
+// characterPosition returns the position of the client's character
+func (client *ServerClient) characterPosition() (rbxfile.ValueVector3, bool) {
+	character := client.Character()
+	if character == nil {
+		return rbxfile.ValueVector3{}, false
+	}
+	return instancePosition(character)
+}
+
+func sortedRegions(units map[StreamInfo][]*datamodel.Instance) []StreamInfo {
+	regions := make([]StreamInfo, 0, len(units))
+	for region := range units {
+		regions = append(regions, region)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		a, b := regions[i], regions[j]
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.Z < b.Z
+	})
+	return regions
+}
+
+// updateStreaming streams in the Workspace children that are close to the
+// client's character and streams out the ones that are far away.
+// If the client doesn't support position-based streaming, every instance is
+// streamed in once and never streamed out.
+func (client *ServerClient) updateStreaming() error {
+	state := client.streaming
+	config := client.Server.Config
+	workspace := client.DataModel.FindService("Workspace")
+	if workspace == nil {
+		return nil
+	}
+	positionBased := client.hasCapability(CapabilityPositionBasedStreaming)
+
+	// Decide what to stream while holding the lock, but write the packets
+	// after releasing it so that replication isn't blocked by the writes
+	state.mutex.Lock()
+	if position, ok := client.characterPosition(); ok {
+		state.focus = position
+	}
+	streamInRadius := config.StreamingTargetRadius
+	if client.hasCapability(CapabilityStreamingPrefetch) {
+		streamInRadius += config.StreamingRegionSize
+	}
+	// Keep an extra region around the target radius so that instances
+	// at the edge aren't streamed in and out repeatedly
+	streamOutRadius := streamInRadius + config.StreamingRegionSize
+
+	streamIn := make(map[StreamInfo][]*datamodel.Instance)
+	for _, unit := range workspace.Children {
+		position, ok := instancePosition(unit)
+		if !ok {
+			continue
+		}
+		region := regionForPosition(position, config.StreamingRegionSize)
+		if _, streamedIn := state.units[unit]; streamedIn {
+			continue
+		}
+		if !positionBased || positionDistance(position, state.focus) <= streamInRadius {
+			streamIn[region] = append(streamIn[region], unit)
+		}
+	}
+
+	streamOut := make(map[StreamInfo][]*datamodel.Instance)
+	for unit, region := range state.units {
+		if unit.Parent() != workspace {
+			// The client will be notified of the parent change by the
+			// normal replication handlers
+			delete(state.units, unit)
+			continue
+		}
+		if !positionBased {
+			continue
+		}
+		position, ok := instancePosition(unit)
+		if ok && positionDistance(position, state.focus) > streamOutRadius {
+			streamOut[region] = append(streamOut[region], unit)
+		}
+	}
+
+	for _, units := range streamOut {
+		for _, unit := range units {
+			delete(state.units, unit)
+		}
+	}
+	for region, units := range streamIn {
+		for _, unit := range units {
+			state.units[unit] = region
+		}
+	}
+	state.mutex.Unlock()
+
+	for _, region := range sortedRegions(streamOut) {
+		err := client.streamOut(region, streamOut[region])
+		if err != nil {
+			return err
+		}
+	}
+	for _, region := range sortedRegions(streamIn) {
+		err := client.streamIn(region, streamIn[region])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startStreaming begins streaming Workspace to the client.
+// It should be called after the join data has been sent.
+func (client *ServerClient) startStreaming() {
+	err := client.updateStreaming()
+	if err != nil {
+		println("streaming error: ", err.Error())
+	}
+
+	ticker := time.NewTicker(client.Server.Config.StreamingUpdateInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := client.updateStreaming()
+				if err != nil {
+					println("streaming error: ", err.Error())
+				}
+			case <-client.RunningContext.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,122 @@
+package peer
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/olebedev/emitter"
+)
+
+// TicketValidator is called when a client submits its ticket. If it returns
+// an error, the client is kicked with the error as the message. It can be used
+// to implement a stand-in for the Roblox authentication service.
+type TicketValidator func(client *ServerClient, ticket *Packet8ALayer) error
+
+// checkTicket verifies the ticket hashes and the security key using
+// the server's SecurityHandler
+func (myServer *CustomServer) checkTicket(ticket *Packet8ALayer) error {
+	security := myServer.Config.Security
+	if security == nil {
+		return nil
+	}
+	if len(myServer.Config.SecurityKeys) != 0 {
+		validKey := false
+		for _, key := range myServer.Config.SecurityKeys {
+			if ticket.SecurityKey == key {
+				validKey = true
+				break
+			}
+		}
+		if !validKey {
+			return errors.New("invalid security key")
+		}
+	}
+	// Studio doesn't send the hashes
+	if myServer.Context.IsStudio {
+		return nil
+	}
+	if ticket.TicketHash != security.GenerateTicketHash(ticket.ClientTicket) {
+		return errors.New("invalid ticket hash")
+	}
+	if ticket.LuauResponse != security.GenerateLuauResponse(ticket.ClientTicket) {
+		return errors.New("invalid Luau response")
+	}
+	return nil
+}
+
+// verifyTicket checks the client's ticket and kicks the client if it
+// is invalid. It returns false if the client was kicked.
+func (client *ServerClient) verifyTicket(ticket *Packet8ALayer) bool {
+	err := client.Server.checkTicket(ticket)
+	if err == nil && client.Server.Config.TicketValidator != nil {
+		err = client.Server.Config.TicketValidator(client, ticket)
+	}
+	if err == nil {
+		return true
+	}
+
+	println("rejecting ticket from", client.Address.String(), err.Error())
+	kickErr := client.Kick(fmt.Sprintf("Authentication failed: %s", err.Error()))
+	if kickErr != nil {
+		println("kick error: ", kickErr.Error())
+	}
+	return false
+}
+
+// sendIDChallenge challenges the client to prove that it is running the
+// client emulated by the server's SecurityHandler. If the client doesn't
+// respond correctly within IDChallengeTimeout, it is kicked.
+func (client *ServerClient) sendIDChallenge() error {
+	if client.Server.Config.Security == nil {
+		return nil
+	}
+	challenge := rand.Uint32()
+	client.challengeMutex.Lock()
+	client.idChallenge = challenge
+	client.idChallengePending = true
+	client.challengeMutex.Unlock()
+
+	timeout := client.Server.Config.IDChallengeTimeout
+	if timeout > 0 {
+		time.AfterFunc(timeout, func() {
+			client.challengeMutex.Lock()
+			pending := client.idChallengePending && client.idChallenge == challenge
+			client.challengeMutex.Unlock()
+			if pending && client.Connected {
+				client.Kick("ID challenge timed out")
+			}
+		})
+	}
+
+	return client.WriteDataPackets(&Packet83_09{
+		Subpacket: &Packet83_09_05{Challenge: challenge},
+	})
+}
+
+func (client *ServerClient) idResponseHandler(e *emitter.Event) {
+	response, ok := e.Args[0].(*Packet83_09).Subpacket.(*Packet83_09_06)
+	if !ok {
+		return
+	}
+	security := client.Server.Config.Security
+	if security == nil {
+		return
+	}
+
+	client.challengeMutex.Lock()
+	valid := client.idChallengePending &&
+		response.Challenge == client.idChallenge &&
+		response.Response == security.GenerateIDResponse(client.idChallenge)
+	client.idChallengePending = false
+	client.challengeMutex.Unlock()
+
+	if !valid {
+		println("invalid ID response from", client.Address.String())
+		err := client.Kick("ID challenge failed")
+		if err != nil {
+			println("kick error: ", err.Error())
+		}
+	}
+}
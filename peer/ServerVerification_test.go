@@ -0,0 +1,28 @@
+package peer
+
+import "testing"
+
+func TestCheckTicket(t *testing.T) {
+	server := &CustomServer{
+		Config:  DefaultServerConfig(),
+		Context: NewCommunicationContext(),
+	}
+	server.Config.Security = Win10Settings()
+
+	ticket := &Packet8ALayer{ClientTicket: "test ticket"}
+	server.Config.Security.PatchTicketPacket(ticket)
+	if err := server.checkTicket(ticket); err != nil {
+		t.Errorf("valid ticket was rejected: %s", err.Error())
+	}
+
+	server.Config.SecurityKeys = []string{"other key"}
+	if server.checkTicket(ticket) == nil {
+		t.Error("ticket with invalid security key was accepted")
+	}
+
+	server.Config.SecurityKeys = nil
+	ticket.TicketHash++
+	if server.checkTicket(ticket) == nil {
+		t.Error("ticket with invalid hash was accepted")
+	}
+}
@@ -208,15 +208,16 @@ func (b *extendedWriter) writeVarLengthString(val string) error {
 }
 
 func (b *extendedWriter) writeLuauProtectedStringRaw(val datamodel.ValueSignedProtectedString, deferred writeDeferredStrings) error {
-	if len(val.Value.Hash) != 0x10 {
-		return errors.New("invalid deferred hash")
+	hash, err := deferred.hashOf(val.Value)
+	if err != nil {
+		return err
 	}
-	err := b.writeASCII(val.Value.Hash)
+	err = b.writeASCII(hash)
 	if err != nil {
 		return err
 	}
 
-	deferred.Defer(val.Value)
+	deferred.Defer(hash, val.Value.Value)
 
 	err = b.writeUintUTF8(uint32(len(val.Signature)))
 	if err != nil {
@@ -225,7 +226,31 @@ func (b *extendedWriter) writeLuauProtectedStringRaw(val datamodel.ValueSignedPr
 	return b.allBytes(val.Signature)
 }
 
+// writeInlineProtectedString writes the bytecode and signature of a script
+// for peers that don't replicate scripts as shared strings
+func (b *extendedWriter) writeInlineProtectedString(val datamodel.ValueSignedProtectedString) error {
+	err := b.writeUintUTF8(uint32(len(val.Value.Value)))
+	if err != nil {
+		return err
+	}
+	err = b.allBytes(val.Value.Value)
+	if err != nil {
+		return err
+	}
+	err = b.writeUintUTF8(uint32(len(val.Signature)))
+	if err != nil {
+		return err
+	}
+	return b.allBytes(val.Signature)
+}
+
 func (b *extendedWriter) writeLuauProtectedString(val datamodel.ValueSignedProtectedString, deferred writeDeferredStrings) error {
+	if val.Value == nil {
+		return errors.New("missing script bytecode")
+	}
+	if !deferred.profile.HasCapability(CapabilityReplicateLuau) {
+		return b.writeInlineProtectedString(val)
+	}
 	return b.writeLuauProtectedStringRaw(val, deferred)
 }
 
@@ -453,7 +478,7 @@ func isCorrectType(val rbxfile.Value, expected uint8) bool {
 	}
 }
 
-func (b *extendedWriter) writeSerializedValueGeneric(val rbxfile.Value, valueType uint8, deferred writeDeferredStrings) error {
+func (b *extendedWriter) writeSerializedValueGeneric(val rbxfile.Value, writer PacketWriter, valueType uint8, deferred writeDeferredStrings) error {
 	if val == nil {
 		return errors.New("can't write nil value")
 	}
@@ -506,7 +531,7 @@ func (b *extendedWriter) writeSerializedValueGeneric(val rbxfile.Value, valueTyp
 	case PropertyTypeComplicatedCFrame:
 		err = b.writeCFrame(val.(rbxfile.ValueCFrame))
 	case PropertyTypeSystemAddress:
-		err = b.writeSystemAddress(val.(datamodel.ValueSystemAddress))
+		err = b.writeSystemAddress(val.(datamodel.ValueSystemAddress), writer.Context().Profile())
 	case PropertyTypeNumberSequence:
 		err = b.writeNumberSequence(val.(datamodel.ValueNumberSequence))
 	case PropertyTypeNumberSequenceKeypoint:
@@ -671,7 +696,14 @@ func (b *extendedWriter) writeNewEnumValue(val datamodel.ValueToken) error {
 	return b.writeUintUTF8(val.Value)
 }
 
-func (b *extendedWriter) writeSystemAddress(val datamodel.ValueSystemAddress) error {
+func (b *extendedWriter) writeSystemAddress(val datamodel.ValueSystemAddress, profile ProtocolProfile) error {
+	if !profile.HasCapability(CapabilitySystemAddressIsPeerId) {
+		err := b.writeUint32BE(uint32(val >> 16))
+		if err != nil {
+			return err
+		}
+		return b.writeUint16BE(uint16(val))
+	}
 	return b.writeVarint64(uint64(val))
 }
 
@@ -731,15 +763,16 @@ func (b *extendedWriter) writePathWaypoint(val datamodel.ValuePathWaypoint) erro
 }
 
 func (b *extendedWriter) writeSharedString(val *datamodel.ValueDeferredString, deferred writeDeferredStrings) error {
-	if len(val.Hash) != 0x10 {
-		return errors.New("invalid deferred hash")
+	hash, err := deferred.hashOf(val)
+	if err != nil {
+		return err
 	}
-	err := b.writeASCII(val.Hash)
+	err = b.writeASCII(hash)
 	if err != nil {
 		return err
 	}
 
-	deferred.Defer(val)
+	deferred.Defer(hash, val.Value)
 
 	return nil
 }
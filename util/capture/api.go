@@ -0,0 +1,41 @@
+package capture
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/fetch"
+)
+
+// Can't use https:// because the site is broken
+const CDNURL = "http://setup.roblox.com/"
+
+// FetchLatestAPI downloads the API dump of the latest Roblox Studio build
+func FetchLatestAPI() (*rbxapijson.Root, error) {
+	robloxApiClient := &fetch.Client{
+		Client: &http.Client{},
+		Config: fetch.Config{
+			Builds:             []fetch.Location{fetch.NewLocation(CDNURL + "DeployHistory.txt")},
+			Latest:             []fetch.Location{fetch.NewLocation(CDNURL + "versionQTStudio")},
+			APIDump:            []fetch.Location{fetch.NewLocation(CDNURL + "$HASH-API-Dump.json")},
+			ReflectionMetadata: []fetch.Location{fetch.NewLocation(CDNURL + "$HASH-RobloxStudio.zip#ReflectionMetadata.xml")},
+			ExplorerIcons:      []fetch.Location{fetch.NewLocation(CDNURL + "$HASH-RobloxStudio.zip#RobloxStudioBeta.exe")},
+		},
+	}
+	latestBuild, err := robloxApiClient.Latest()
+	if err != nil {
+		return nil, err
+	}
+	return robloxApiClient.APIDump(latestBuild.Hash)
+}
+
+// ReadAPIFile reads a JSON API dump from a file
+func ReadAPIFile(name string) (*rbxapijson.Root, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return rbxapijson.Decode(file)
+}
@@ -0,0 +1,192 @@
+// Package capture reads Roblox conversations from packet captures and dumps
+// their DataModels. It doesn't depend on the GUI.
+package capture
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/olebedev/emitter"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+)
+
+type PacketProvider interface {
+	Layers() *emitter.Emitter
+	Errors() *emitter.Emitter
+}
+
+type Conversation struct {
+	Client       *net.UDPAddr
+	Server       *net.UDPAddr
+	ClientReader PacketProvider
+	ServerReader PacketProvider
+	Context      *peer.CommunicationContext
+}
+
+type Conversations interface {
+	ConversationFor(source *net.UDPAddr, dest *net.UDPAddr, payload []byte) *Conversation
+	SetProgress(int)
+}
+
+func AddressEq(a *net.UDPAddr, b *net.UDPAddr) bool {
+	return a.Port == b.Port && a.IP.Equal(b.IP)
+}
+
+// NewConversation starts a new conversation if payload begins a RakNet handshake
+// and returns nil otherwise. apiDump may be nil, in which case enum names
+// can't be resolved.
+func NewConversation(source *net.UDPAddr, dest *net.UDPAddr, payload []byte, apiDump *rbxapijson.Root) *Conversation {
+	if len(payload) < 1 || payload[0] != 0x7B {
+		return nil
+	}
+	isHandshake := peer.IsOfflineMessage(payload)
+	if !isHandshake {
+		return nil
+	}
+
+	newContext := peer.NewCommunicationContext()
+	newContext.APIDump = apiDump
+	newContext.Journal = peer.NewReplicationJournal(peer.DefaultReplicationJournalLimit)
+	newContext.DataModel.History = datamodel.NewPropertyHistory(datamodel.DefaultPropertyHistoryLimit)
+	clientR := peer.NewPacketReader()
+	serverR := peer.NewPacketReader()
+	clientR.SetContext(newContext)
+	serverR.SetContext(newContext)
+	clientR.SetIsClient(true)
+	clientR.BindDataModelHandlers()
+	serverR.BindDataModelHandlers()
+	newConv := &Conversation{
+		Client:       source,
+		Server:       dest,
+		ClientReader: clientR,
+		ServerReader: serverR,
+		Context:      newContext,
+	}
+	return newConv
+}
+
+// Session collects the conversations of a capture
+type Session struct {
+	Conversations []*Conversation
+	// APIDump is passed to new conversations. It may be nil.
+	APIDump *rbxapijson.Root
+}
+
+func (session *Session) ConversationFor(source *net.UDPAddr, dest *net.UDPAddr, payload []byte) *Conversation {
+	for _, conv := range session.Conversations {
+		if AddressEq(source, conv.Client) && AddressEq(dest, conv.Server) {
+			return conv
+		}
+		if AddressEq(source, conv.Server) && AddressEq(dest, conv.Client) {
+			return conv
+		}
+	}
+
+	newConv := NewConversation(source, dest, payload, session.APIDump)
+	if newConv != nil {
+		session.Conversations = append(session.Conversations, newConv)
+	}
+	return newConv
+}
+
+func (session *Session) SetProgress(int) {}
+
+func SrcAndDestFromGoPacket(packet gopacket.Packet) (*net.UDPAddr, *net.UDPAddr) {
+	var srcIP, dstIP net.IP
+	if ipv4, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		srcIP = ipv4.SrcIP
+		dstIP = ipv4.DstIP
+	} else if ipv6, ok := packet.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		srcIP = ipv6.SrcIP
+		dstIP = ipv6.DstIP
+	}
+	return &net.UDPAddr{
+		IP:   srcIP,
+		Port: int(packet.Layer(layers.LayerTypeUDP).(*layers.UDP).SrcPort),
+		Zone: "udp",
+	}, &net.UDPAddr{
+		IP:   dstIP,
+		Port: int(packet.Layer(layers.LayerTypeUDP).(*layers.UDP).DstPort),
+		Zone: "udp",
+	}
+}
+
+func NewLayers(source *net.UDPAddr, dest *net.UDPAddr, fromClient bool) *peer.PacketLayers {
+	return &peer.PacketLayers{
+		Root: peer.RootLayer{
+			Source:      source,
+			Destination: dest,
+			FromClient:  fromClient,
+			FromServer:  !fromClient,
+		},
+	}
+}
+
+func CaptureFromSource(ctx context.Context, convs Conversations, packetSource *gopacket.PacketSource) error {
+	var progress int
+	packetChan := packetSource.Packets()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case packet, ok := <-packetChan:
+			if !ok {
+				return nil
+			}
+			progress++
+
+			if packet.ApplicationLayer() == nil ||
+				(packet.Layer(layers.LayerTypeIPv4) == nil && packet.Layer(layers.LayerTypeIPv6) == nil) ||
+				packet.Layer(layers.LayerTypeUDP) == nil {
+				continue
+			}
+			payload := packet.ApplicationLayer().Payload()
+			if len(payload) == 0 {
+				continue
+			}
+
+			src, dest := SrcAndDestFromGoPacket(packet)
+			conv := convs.ConversationFor(src, dest, payload)
+			if conv == nil {
+				continue // Not a RakNet packet
+			}
+			fromClient := AddressEq(src, conv.Client)
+
+			layers := NewLayers(src, dest, fromClient)
+			var reader PacketProvider
+			if fromClient {
+				reader = conv.ClientReader
+			} else {
+				reader = conv.ServerReader
+			}
+			reader.(peer.PacketReader).ReadPacket(payload, layers)
+			convs.SetProgress(progress)
+		}
+	}
+}
+
+// CaptureFromFile reads a pcap or pcapng capture without linking to libpcap
+func CaptureFromFile(ctx context.Context, convs Conversations, file io.ReadSeeker) error {
+	var packetSource *gopacket.PacketSource
+	reader, err := pcapgo.NewReader(file)
+	if err == nil {
+		packetSource = gopacket.NewPacketSource(reader, reader.LinkType())
+	} else {
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		ngReader, err := pcapgo.NewNgReader(file, pcapgo.DefaultNgReaderOptions)
+		if err != nil {
+			return err
+		}
+		packetSource = gopacket.NewPacketSource(ngReader, ngReader.LinkType())
+	}
+	return CaptureFromSource(ctx, convs, packetSource)
+}
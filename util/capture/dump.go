@@ -0,0 +1,172 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/robloxapi/rbxfile"
+)
+
+type InstanceProperty struct {
+	Instance *rbxfile.Instance
+	Name     string
+}
+
+func findScripts(instances []*rbxfile.Instance, propertyList []InstanceProperty) []InstanceProperty {
+	for _, instance := range instances {
+		for name, property := range instance.Properties {
+			thisType := property.Type()
+			if thisType == datamodel.TypeSignedProtectedString {
+				propertyList = append(propertyList, InstanceProperty{
+					Instance: instance,
+					Name:     name,
+				})
+			}
+		}
+		propertyList = findScripts(instance.Children, propertyList)
+	}
+	return propertyList
+}
+
+func instName(instance *rbxfile.Instance) string {
+	name := instance.Get("Name")
+	if name == nil {
+		return instance.ClassName
+	}
+	var nameStr rbxfile.ValueString
+	var ok bool
+	if nameStr, ok = name.(rbxfile.ValueString); !ok {
+		return instance.ClassName
+	}
+	return string(nameStr)
+}
+
+func getFullName(instance *rbxfile.Instance) string {
+	if instance == nil {
+		return "nil"
+	}
+	parts := make([]string, 0, 8)
+	for instance != nil {
+		parts = append([]string{instName(instance)}, parts...)
+		instance = instance.Parent()
+	}
+	var builder strings.Builder
+	for _, part := range parts {
+		builder.WriteByte('.')
+		builder.WriteString(part)
+	}
+	return builder.String()[1:]
+}
+
+func dumpScripts(location string, instances []*rbxfile.Instance, encountered map[string]int) error {
+	instList := findScripts(instances, nil)
+
+	for _, instProp := range instList {
+		script := instProp.Instance.Properties[instProp.Name].(datamodel.ValueSignedProtectedString)
+		if script.Value == nil {
+			continue
+		}
+		instFullName := getFullName(instProp.Instance)
+		name := fmt.Sprintf("%s/%s.rbxc", location, instFullName)
+		if count, ok := encountered[name]; ok {
+			oldName := name
+			name = fmt.Sprintf("%s/%s.%d.rbxc", location, instFullName, count)
+			encountered[oldName] = count + 1
+		} else {
+			encountered[name] = 1
+		}
+
+		err := writeDumpFile(name, func(w io.Writer) error {
+			_, err := w.Write([]byte(script.Value.Value))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDumpFile creates or truncates the file and writes to it using write
+func writeDumpFile(name string, write func(io.Writer) error) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// WriteDataModelDump writes the DataModel as RBXL and RBXLX places along with
+// the scripts (RBXC) and the script keys of the context to location.
+// Both places contain the script bytecode. Every file is attempted even if
+// writing another one fails.
+func WriteDataModelDump(context *peer.CommunicationContext, model *datamodel.DataModel, location string) error {
+	var failures []string
+	fail := func(format string, err error) {
+		failures = append(failures, fmt.Sprintf(format, err.Error()))
+	}
+
+	err := writeDumpFile(location+"/datamodel.rbxl", model.WriteRbxl)
+	if err != nil {
+		fail("serializing binary place: %s", err)
+	}
+	err = writeDumpFile(location+"/datamodel.rbxlx", model.WriteRbxlx)
+	if err != nil {
+		fail("serializing place: %s", err)
+	}
+	err = writeDumpFile(location+"/scriptKeys", func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Script key: %d\nCore script key: %d", context.ScriptKey, context.CoreScriptKey)
+		return err
+	})
+	if err != nil {
+		fail("dumping script keys: %s", err)
+	}
+	err = dumpScripts(location, model.ToRbxfile().Instances, make(map[string]int))
+	if err != nil {
+		fail("dumping scripts: %s", err)
+	}
+
+	if len(failures) != 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// FilterServices returns a DataModel that only contains the given services.
+// If services is empty, model is returned as is.
+func FilterServices(model *datamodel.DataModel, services []string) *datamodel.DataModel {
+	if len(services) == 0 {
+		return model
+	}
+	filtered := datamodel.New()
+	for _, name := range services {
+		service := model.FindService(name)
+		if service != nil {
+			filtered.Instances = append(filtered.Instances, service)
+		}
+	}
+	return filtered
+}
+
+// ReportUnresolvedReferences writes the references of the conversation
+// whose target instance was never replicated to w
+func ReportUnresolvedReferences(w io.Writer, index int, conv *Conversation) {
+	pending := conv.Context.UnresolvedReferences.Pending()
+	if len(pending) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Conversation %d has %d unresolved references:\n", index, len(pending))
+	for _, ref := range pending {
+		fmt.Fprintf(w, "\t%s\n", ref.String())
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Gskartwii/roblox-dissector/util/capture"
+)
+
+// parseList splits a comma-separated list, ignoring empty items
+func parseList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func dump(session *capture.Session, location string, selected map[int]bool, services []string, until uint64) error {
+	for i, conv := range session.Conversations {
+		index := i + 1
+		if len(selected) != 0 && !selected[index] {
+			continue
+		}
+		model := conv.Context.DataModel
+		if until != 0 {
+			model = conv.Context.Journal.Snapshot(model, until)
+		}
+		model = capture.FilterServices(model, services)
+
+		convLocation := filepath.Join(location, fmt.Sprintf("conversation%d", index))
+		err := os.MkdirAll(convLocation, 0755)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dumping conversation %d (%s <-> %s) to %s\n", index, conv.Client, conv.Server, convLocation)
+		err = capture.WriteDataModelDump(conv.Context, model, convLocation)
+		if err != nil {
+			return fmt.Errorf("conversation %d: %w", index, err)
+		}
+		capture.ReportUnresolvedReferences(os.Stdout, index, conv)
+	}
+	return nil
+}
+
+func main() {
+	conversationList := flag.String("conversations", "", "comma-separated `indices` of the conversations to dump, starting from 1 (default all)")
+	serviceList := flag.String("services", "", "comma-separated `class names` of the services to dump (default all)")
+	until := flag.Uint64("until", 0, "dump the DataModel as it was after the packet with this unique `ID` (default end of capture)")
+	apiFile := flag.String("api", "", "path to a JSON API dump used for resolving enum names")
+	fetchAPI := flag.Bool("fetch-api", false, "download the API dump of the latest Roblox build for resolving enum names")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <capture file> <output directory>\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Dumps the DataModel of each conversation in a capture file.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	filename, location := flag.Arg(0), flag.Arg(1)
+
+	selected := make(map[int]bool)
+	for _, item := range parseList(*conversationList) {
+		index, err := strconv.Atoi(item)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid conversation index %q\n", item)
+			os.Exit(2)
+		}
+		selected[index] = true
+	}
+
+	session := &capture.Session{}
+	var err error
+	if *apiFile != "" {
+		session.APIDump, err = capture.ReadAPIFile(*apiFile)
+	} else if *fetchAPI {
+		session.APIDump, err = capture.FetchLatestAPI()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	err = capture.CaptureFromFile(context.Background(), session, file)
+	file.Close()
+	if err != nil {
+		panic(err)
+	}
+
+	err = dump(session, location, selected, parseList(*serviceList), *until)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Gskartwii/roblox-dissector/datamodel"
+	"github.com/Gskartwii/roblox-dissector/peer"
+	"github.com/olebedev/emitter"
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/bin"
+	"github.com/robloxapi/rbxfile/xml"
+)
+
+type joinDataConfig struct {
+	ClassName           string `json:"className"`
+	ReplicateProperties bool   `json:"replicateProperties"`
+	ReplicateChildren   bool   `json:"replicateChildren"`
+}
+
+type serverConfig struct {
+	// Schema and Place are relative to the directory of the config file.
+	// Place may be a .rbxl or .rbxlx file or a project directory.
+	Schema     string `json:"schema"`
+	Place      string `json:"place"`
+	Port       uint16 `json:"port"`
+	GUID       uint64 `json:"guid"`
+	MaxPlayers int    `json:"maxPlayers"`
+	// TypeMapping maps API dump value types to network types if Schema
+	// is an API dump. It is merged with the default mapping.
+	TypeMapping map[string]uint8 `json:"typeMapping"`
+	// JoinData overrides the default list of replicated services
+	JoinData []joinDataConfig `json:"joinData"`
+	// Flags overrides the values of ID_DICTIONARY_FORMAT flags
+	Flags map[string]bool `json:"flags"`
+	// The following settings use the server defaults if they are missing
+	Capabilities       *uint64 `json:"capabilities"`
+	FilteringEnabled   *bool   `json:"filteringEnabled"`
+	CharacterAutoSpawn *bool   `json:"characterAutoSpawn"`
+	StreamingEnabled   *bool   `json:"streamingEnabled"`
+	// CharacterTemplate is the path of the character model in the place
+	CharacterTemplate string `json:"characterTemplate"`
+	// Distances are in studs. Zero values use the server defaults.
+	StreamingRegionSize   float32 `json:"streamingRegionSize"`
+	StreamingTargetRadius float32 `json:"streamingTargetRadius"`
+	// Bans are loaded into the server's ban list
+	BannedAddresses []string `json:"bannedAddresses"`
+	BannedPlayerIDs []int64  `json:"bannedPlayerIds"`
+	// PhysicsBroadcastInterval is in milliseconds. A negative value disables
+	// physics broadcasting.
+	PhysicsBroadcastInterval int `json:"physicsBroadcastInterval"`
+}
+
+func loadConfig(name string) (*serverConfig, error) {
+	config := &serverConfig{Port: 53640}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(name)
+	if config.Schema != "" && !filepath.IsAbs(config.Schema) {
+		config.Schema = filepath.Join(dir, config.Schema)
+	}
+	if config.Place != "" && !filepath.IsAbs(config.Place) {
+		config.Place = filepath.Join(dir, config.Place)
+	}
+	return config, nil
+}
+
+func loadSchema(name string, typeMapping map[string]uint8) (*peer.NetworkSchema, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if typeMapping != nil {
+		typeMapping = peer.MergeAPITypeMapping(typeMapping)
+	}
+	schema, warnings, err := peer.LoadSchemaWithMapping(file, typeMapping)
+	for _, warning := range warnings {
+		log.Printf("Schema generation warning: %s", warning)
+	}
+	return schema, err
+}
+
+func loadPlace(name string) (*rbxfile.Root, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return datamodel.ReadProject(name)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if strings.HasSuffix(strings.ToLower(name), ".rbxl") {
+		return bin.DeserializePlace(file, nil)
+	}
+	return xml.Deserialize(file, nil)
+}
+
+func bindClientLogging(client *peer.ServerClient) {
+	address := client.Address.String()
+	log.Printf("%s: connected", address)
+	client.DefaultPacketReader.ErrorEmitter.On("*", func(e *emitter.Event) {
+		layers := e.Args[0].(*peer.PacketLayers)
+		log.Printf("%s: %s error: %s", address, e.OriginalTopic, layers.Error.Error())
+	}, emitter.Void)
+	client.GenericEvents.On("disconnected", func(e *emitter.Event) {
+		log.Printf("%s: disconnected with reason %d", address, e.Args[1].(int32))
+	}, emitter.Void)
+	client.GenericEvents.On("rejected", func(e *emitter.Event) {
+		change := e.Args[0].(*peer.ClientChange)
+		if change.Instance == nil {
+			log.Printf("%s: rejected change to unknown instance", address)
+			return
+		}
+		log.Printf("%s: rejected change to %s %s", address, change.Instance.GetFullName(), change.Name)
+	}, emitter.Void)
+}
+
+func main() {
+	configName := flag.String("config", "sala-server.json", "Path to server config file")
+	flag.Parse()
+
+	config, err := loadConfig(*configName)
+	if err != nil {
+		log.Fatalf("Failed to load config: %s", err.Error())
+	}
+	if config.Schema == "" || config.Place == "" {
+		log.Fatal("Config must specify a schema and a place")
+	}
+	schema, err := loadSchema(config.Schema, config.TypeMapping)
+	if err != nil {
+		log.Fatalf("Failed to load schema: %s", err.Error())
+	}
+	place, err := loadPlace(config.Place)
+	if err != nil {
+		log.Fatalf("Failed to load place: %s", err.Error())
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	instanceDictionary := datamodel.NewInstanceDictionary(1)
+	dataModel := datamodel.FromRbxfile(instanceDictionary, place)
+	peer.NormalizeDataModel(dataModel, schema)
+
+	server, err := peer.NewCustomServer(context.Background(), config.Port, schema, dataModel, instanceDictionary)
+	if err != nil {
+		log.Fatalf("Failed to create server: %s", err.Error())
+	}
+	server.Context.InstancesByReference.Populate(dataModel.Instances)
+	if config.GUID != 0 {
+		server.GUID = config.GUID
+	}
+	serverConfig := server.Config
+	serverConfig.MaxPlayers = config.MaxPlayers
+	if config.JoinData != nil {
+		serverConfig.JoinData = make([]peer.JoinDataConfig, len(config.JoinData))
+		for i, service := range config.JoinData {
+			serverConfig.JoinData[i] = peer.JoinDataConfig{
+				ClassName:           service.ClassName,
+				ReplicateProperties: service.ReplicateProperties,
+				ReplicateChildren:   service.ReplicateChildren,
+			}
+		}
+	}
+	for name, value := range config.Flags {
+		serverConfig.Params[name] = value
+	}
+	if config.Capabilities != nil {
+		serverConfig.Capabilities = *config.Capabilities
+	}
+	if config.FilteringEnabled != nil {
+		serverConfig.FilteringEnabled = *config.FilteringEnabled
+	}
+	if config.CharacterAutoSpawn != nil {
+		serverConfig.CharacterAutoSpawn = *config.CharacterAutoSpawn
+	}
+	if config.CharacterTemplate != "" {
+		serverConfig.CharacterTemplate = config.CharacterTemplate
+	}
+	if config.StreamingEnabled != nil {
+		serverConfig.StreamingEnabled = *config.StreamingEnabled
+	}
+	if config.StreamingRegionSize != 0 {
+		serverConfig.StreamingRegionSize = config.StreamingRegionSize
+	}
+	if config.StreamingTargetRadius != 0 {
+		serverConfig.StreamingTargetRadius = config.StreamingTargetRadius
+	}
+	if config.PhysicsBroadcastInterval < 0 {
+		serverConfig.PhysicsBroadcastInterval = 0
+	} else if config.PhysicsBroadcastInterval != 0 {
+		serverConfig.PhysicsBroadcastInterval = time.Duration(config.PhysicsBroadcastInterval) * time.Millisecond
+	}
+
+	for _, address := range config.BannedAddresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			log.Fatalf("Invalid banned address: %s", address)
+		}
+		server.Bans.BanAddress(ip, "You are banned from this server")
+	}
+	for _, playerID := range config.BannedPlayerIDs {
+		server.Bans.BanPlayerID(playerID, "You are banned from this server")
+	}
+
+	server.ClientEmitter.On("client", func(e *emitter.Event) {
+		bindClientLogging(e.Args[0].(*peer.ServerClient))
+	}, emitter.Void)
+	server.ClientEmitter.On("rejected", func(e *emitter.Event) {
+		log.Printf("%s: rejected: %s", e.Args[0].(*net.UDPAddr).String(), e.Args[1].(string))
+	}, emitter.Void)
+
+	log.Printf("Serving %s on port %d", config.Place, config.Port)
+	err = server.Start()
+	if err != nil {
+		log.Fatalf("Server stopped: %s", err.Error())
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Gskartwii/roblox-dissector/peer"
+)
+
+func main() {
+	outFileName := flag.String("o", "", "Path to output file (default stdout)")
+	packageName := flag.String("package", "instances", "Name of the generated package")
+	typesFileName := flag.String("types", "", "Path to a JSON file that maps API dump value types to network types")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <schema file>\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Generates typed Go accessors for the classes in a network schema, JSON schema or API dump.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var typeMapping map[string]uint8
+	if *typesFileName != "" {
+		typesFile, err := os.Open(*typesFileName)
+		if err != nil {
+			panic(err)
+		}
+		typeMapping, err = peer.ParseAPITypeMapping(typesFile)
+		typesFile.Close()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	schemaFile, err := os.Open(flag.Arg(0))
+	if err != nil {
+		panic(err)
+	}
+	schema, warnings, err := peer.LoadSchemaWithMapping(schemaFile, typeMapping)
+	schemaFile.Close()
+	if err != nil {
+		panic(err)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, "Schema generation warning:", warning)
+	}
+
+	out := os.Stdout
+	if *outFileName != "" {
+		out, err = os.Create(*outFileName)
+		if err != nil {
+			panic(err)
+		}
+		defer out.Close()
+	}
+	err = schema.GenerateAccessors(out, *packageName)
+	if err != nil {
+		panic(err)
+	}
+}